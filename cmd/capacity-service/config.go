@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+
+	"github.com/supergiant/capacity/pkg/kubescaler"
+)
+
+const configUsage = "usage: capacity-service config example [--format FORMAT]"
+
+type configExampleArgs struct {
+	Format string `arg:"--format" help:"config format [json yaml toml]"`
+}
+
+// runConfigCommand handles the 'config' subcommands. They are parsed manually as
+// go-arg doesn't support subcommands.
+func runConfigCommand(cmdArgs []string) {
+	if len(cmdArgs) == 0 || cmdArgs[0] != "example" {
+		fmt.Fprintln(os.Stderr, configUsage)
+		os.Exit(2)
+	}
+
+	args := configExampleArgs{
+		Format: kubescaler.FormatJSON,
+	}
+	p, err := arg.NewParser(arg.Config{Program: "capacity-service config example"}, &args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err = p.Parse(cmdArgs[1:]); err != nil {
+		if err == arg.ErrHelp {
+			p.WriteHelp(os.Stdout)
+			os.Exit(0)
+		}
+		p.Fail(err.Error())
+	}
+
+	if err = kubescaler.WriteExampleConfig(os.Stdout, args.Format); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
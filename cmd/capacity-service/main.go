@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/alexflint/go-arg"
@@ -12,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/auth"
 	"github.com/supergiant/capacity/pkg/capacityserver"
 	"github.com/supergiant/capacity/pkg/kubescaler"
 	"github.com/supergiant/capacity/pkg/log"
@@ -19,14 +21,28 @@ import (
 )
 
 type args struct {
-	KubescalerConfig   string `arg:"--kubescaler-config,   env:CAPACITY_KUBESCALER_CONFIG"   help:"path to a kubescaler config"`
-	ConfigMapName      string `arg:"--configmap-name,      env:CAPACITY_CONFIGMAP_NAME"      help:"name of configMap with the 'kubescaler.conf' file"`
-	ConfigMapNamespace string `arg:"--configmap-namespace, env:CAPACITY_CONFIGMAP_NAMESPACE" help:"namespace of configMap with kubescaler config"`
-	KubeConfig         string `arg:"--kubeconfig,          env:CAPACITY_KUBE_CONFIG"         help:"path to a kubeconfig file, needs for building a kubernetes client"`
-	ListenAddr         string `arg:"--listen-addr,         env:CAPACITY_LISTEN_ADDR"         help:"address to listen on, pass as a addr:port"`
-	LogLevel           string `arg:"--log-level,           env:CAPACITY_LOG_LEVEL"           help:"logging verbosity [debug info warn error fatal panic]"`
-	LogFormat          string `arg:"--log-format,          env:CAPACITY_LOG_LEVEL"           help:"logging format [txt json]"`
-	LogHooks           string `arg:"--log-hooks,           env:CAPACITY_LOG_HOOKS"           help:"list of comma-separated log providers (syslog)"`
+	KubescalerConfig   string `arg:"--kubescaler-config,      env:CAPACITY_KUBESCALER_CONFIG"      help:"path to a kubescaler config"`
+	ConfigMapName      string `arg:"--configmap-name,         env:CAPACITY_CONFIGMAP_NAME"         help:"name of configMap with the 'kubescaler.conf' file"`
+	ConfigMapNamespace string `arg:"--configmap-namespace,    env:CAPACITY_CONFIGMAP_NAMESPACE"    help:"namespace of configMap with kubescaler config"`
+	ConfigMapKey       string `arg:"--configmap-key,          env:CAPACITY_CONFIGMAP_KEY"          help:"configMap key with kubescaler config, its extension defines a format [conf json yaml toml]"`
+	ConfigResource     string `arg:"--config-resource,        env:CAPACITY_CONFIG_RESOURCE"        help:"CapacityConfig resource to use instead of a config file/configMap, pass as a namespace/name"`
+	ConfigHistoryLimit int    `arg:"--config-history-limit,   env:CAPACITY_CONFIG_HISTORY_LIMIT"   help:"number of config revisions to keep, disabled if zero"`
+	WorkerResources    bool   `arg:"--worker-resources,       env:CAPACITY_WORKER_RESOURCES"       help:"maintain a Worker resource per machine, needs the Worker CRD to be installed"`
+	KubeConfig         string `arg:"--kubeconfig,             env:CAPACITY_KUBE_CONFIG"            help:"path to a kubeconfig file, needs for building a kubernetes client"`
+	ListenAddr         string `arg:"--listen-addr,            env:CAPACITY_LISTEN_ADDR"            help:"address to listen on, pass as a addr:port"`
+	LogLevel           string `arg:"--log-level,              env:CAPACITY_LOG_LEVEL"              help:"logging verbosity [debug info warn error fatal panic]"`
+	LogFormat          string `arg:"--log-format,             env:CAPACITY_LOG_LEVEL"              help:"logging format [txt json]"`
+	LogHooks           string `arg:"--log-hooks,              env:CAPACITY_LOG_HOOKS"              help:"list of comma-separated log providers (syslog)"`
+	AuthModes          string `arg:"--auth-modes,             env:CAPACITY_AUTH_MODES"             help:"list of comma-separated authentication modes [token tokenreview x509], disabled if empty"`
+	AuthTokenSecret    string `arg:"--auth-token-secret,      env:CAPACITY_AUTH_TOKEN_SECRET"      help:"secret with static tokens, pass as a namespace/name"`
+	AuthTokenSecretKey string `arg:"--auth-token-secret-key,  env:CAPACITY_AUTH_TOKEN_SECRET_KEY"  help:"secret key with 'token,user,role' lines"`
+	AuthAdminGroups    string `arg:"--auth-admin-groups,      env:CAPACITY_AUTH_ADMIN_GROUPS"      help:"list of comma-separated groups granted the admin role"`
+	AuthOperatorGroups string `arg:"--auth-operator-groups,   env:CAPACITY_AUTH_OPERATOR_GROUPS"   help:"list of comma-separated groups granted the operator role"`
+	AuthReadOnlyGroups string `arg:"--auth-readonly-groups,   env:CAPACITY_AUTH_READONLY_GROUPS"   help:"list of comma-separated groups granted the read-only role"`
+	TLSCertFile        string `arg:"--tls-cert-file,          env:CAPACITY_TLS_CERT_FILE"          help:"path to a x509 certificate for https, reloaded on changes"`
+	TLSKeyFile         string `arg:"--tls-key-file,           env:CAPACITY_TLS_KEY_FILE"           help:"path to a x509 private key matching --tls-cert-file"`
+	TLSClientCAFile    string `arg:"--tls-client-ca-file,     env:CAPACITY_TLS_CLIENT_CA_FILE"     help:"path to a CA bundle for verifying client certificates"`
+	TLSSelfSigned      bool   `arg:"--tls-self-signed,        env:CAPACITY_TLS_SELF_SIGNED"        help:"generate a self-signed certificate if cert/key files don't exist"`
 }
 
 func (args) Version() string {
@@ -34,12 +50,20 @@ func (args) Version() string {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfigCommand(os.Args[2:])
+		return
+	}
+
 	args := args{
 		ListenAddr:         ":8081",
 		LogLevel:           "info",
 		LogFormat:          "txt",
 		ConfigMapName:      api.DefaultConfigMapName,
 		ConfigMapNamespace: api.DefaultConfigMapNamespace,
+		ConfigMapKey:       api.DefaultConfigMapKey,
+		ConfigHistoryLimit: kubescaler.DefaultHistoryLimit,
+		AuthTokenSecretKey: auth.DefaultTokenSecretKey,
 	}
 	arg.MustParse(&args)
 
@@ -57,9 +81,20 @@ func main() {
 			ConfigFile:         args.KubescalerConfig,
 			ConfigMapName:      args.ConfigMapName,
 			ConfigMapNamespace: args.ConfigMapNamespace,
+			ConfigMapKey:       args.ConfigMapKey,
 			Kubeconfig:         args.KubeConfig,
+			ConfigResource:     args.ConfigResource,
+			ConfigHistoryLimit: args.ConfigHistoryLimit,
+			WorkerResources:    args.WorkerResources,
 		},
 		ListenAddr: args.ListenAddr,
+		Auth:       authConfig(args),
+		TLS: capacityserver.TLSConfig{
+			CertFile:     args.TLSCertFile,
+			KeyFile:      args.TLSKeyFile,
+			ClientCAFile: args.TLSClientCAFile,
+			SelfSigned:   args.TLSSelfSigned,
+		},
 	})
 	if err != nil {
 		log.Fatalf("capacityserver: %v\n", err)
@@ -99,3 +134,42 @@ func configureLogging(level, format string) {
 		})
 	}
 }
+
+func authConfig(args args) auth.Config {
+	conf := auth.Config{
+		Modes:          splitList(args.AuthModes),
+		TokenSecretKey: args.AuthTokenSecretKey,
+		GroupRoles:     auth.GroupRoles{},
+	}
+
+	if parts := strings.SplitN(args.AuthTokenSecret, "/", 2); len(parts) == 2 {
+		conf.TokenSecretNamespace, conf.TokenSecretName = parts[0], parts[1]
+	} else {
+		conf.TokenSecretNamespace, conf.TokenSecretName = api.DefaultConfigMapNamespace, args.AuthTokenSecret
+	}
+
+	// more permissive roles take precedence
+	for role, groups := range map[auth.Role]string{
+		auth.RoleReadOnly: args.AuthReadOnlyGroups,
+		auth.RoleOperator: args.AuthOperatorGroups,
+		auth.RoleAdmin:    args.AuthAdminGroups,
+	} {
+		for _, group := range splitList(groups) {
+			if role.Allows(conf.GroupRoles[group]) {
+				conf.GroupRoles[group] = role
+			}
+		}
+	}
+
+	return conf
+}
+
+func splitList(s string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
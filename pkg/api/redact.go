@@ -0,0 +1,84 @@
+package api
+
+import (
+	"reflect"
+)
+
+// RedactedValue replaces sensitive values in API responses and logs. If it's sent back
+// in a config update, the stored value is kept unchanged.
+const RedactedValue = "******"
+
+// sensitiveTag marks string fields that shouldn't be exposed.
+const sensitiveTag = "sensitive"
+
+// Redacted returns a copy of the config with masked sensitive values. Provider parameters
+// are masked if they are listed in the sensitiveKeys.
+func (c Config) Redacted(sensitiveKeys []string) Config {
+	if c.Provider != nil {
+		provider := make(map[string]string, len(c.Provider))
+		for k, v := range c.Provider {
+			provider[k] = v
+		}
+		for _, k := range sensitiveKeys {
+			if provider[k] != "" {
+				provider[k] = RedactedValue
+			}
+		}
+		c.Provider = provider
+	}
+
+	if c.SupergiantV1Config != nil {
+		sgConf := *c.SupergiantV1Config
+		redactFields(reflect.ValueOf(&sgConf).Elem())
+		c.SupergiantV1Config = &sgConf
+	}
+
+	return c
+}
+
+// WithRedactedFrom returns a copy of the config where masked sensitive values are replaced
+// with the ones from the stored config.
+func (c Config) WithRedactedFrom(stored Config, sensitiveKeys []string) Config {
+	if c.Provider != nil {
+		provider := make(map[string]string, len(c.Provider))
+		for k, v := range c.Provider {
+			provider[k] = v
+		}
+		for _, k := range sensitiveKeys {
+			if provider[k] == RedactedValue {
+				provider[k] = stored.Provider[k]
+			}
+		}
+		c.Provider = provider
+	}
+
+	if c.SupergiantV1Config != nil {
+		sgConf := *c.SupergiantV1Config
+		if stored.SupergiantV1Config != nil {
+			restoreFields(reflect.ValueOf(&sgConf).Elem(), reflect.ValueOf(stored.SupergiantV1Config).Elem())
+		}
+		c.SupergiantV1Config = &sgConf
+	}
+
+	return c
+}
+
+func redactFields(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		if isSensitive(v.Type().Field(i)) && v.Field(i).String() != "" {
+			v.Field(i).SetString(RedactedValue)
+		}
+	}
+}
+
+func restoreFields(v, stored reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		if isSensitive(v.Type().Field(i)) && v.Field(i).String() == RedactedValue {
+			v.Field(i).SetString(stored.Field(i).String())
+		}
+	}
+}
+
+func isSensitive(f reflect.StructField) bool {
+	return f.Type.Kind() == reflect.String && f.Tag.Get(sensitiveTag) == "true"
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/supergiant/capacity/pkg/cron"
+)
+
+// MaxScheduleDuration limits a schedule window, so the active window is found
+// by checking a week of minutes at most.
+const MaxScheduleDuration = 7 * 24 * time.Hour
+
+// Active reports whether the time is within one of the schedule windows.
+func (s Schedule) Active(now time.Time) (bool, error) {
+	expr, err := cron.Parse(s.Cron)
+	if err != nil {
+		return false, err
+	}
+	d, err := time.ParseDuration(s.Duration)
+	if err != nil {
+		return false, errors.Wrap(err, "parse duration")
+	}
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		return false, errors.Wrap(err, "load time zone")
+	}
+
+	// look for a window start in the past duration
+	start := now.In(loc).Truncate(time.Minute)
+	for ; now.Sub(start) < d; start = start.Add(-time.Minute) {
+		if expr.Matches(start) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Scheduled returns the config with overrides of the first active schedule applied.
+// A nil schedule is returned if none of them is active.
+func (c Config) Scheduled(now time.Time) (Config, *Schedule) {
+	for i := range c.Schedules {
+		// invalid schedules are rejected on validation
+		if active, _ := c.Schedules[i].Active(now); !active {
+			continue
+		}
+
+		s := &c.Schedules[i]
+		if s.WorkersCountMin != nil {
+			c.WorkersCountMin = *s.WorkersCountMin
+		}
+		if s.WorkersCountMax != nil {
+			c.WorkersCountMax = *s.WorkersCountMax
+		}
+		if s.Paused != nil {
+			c.Paused = s.Paused
+		}
+		return c, s
+	}
+	return c, nil
+}
+
+func (c Config) validateSchedules() field.ErrorList {
+	errs := field.ErrorList{}
+	for i, s := range c.Schedules {
+		fldPath := field.NewPath("schedules").Index(i)
+
+		if _, err := cron.Parse(s.Cron); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("cron"), s.Cron, errors.Cause(err).Error()))
+		}
+		if s.Duration == "" {
+			errs = append(errs, field.Required(fldPath.Child("duration"), "window length, e.g. '10h'"))
+		} else if d, err := time.ParseDuration(s.Duration); err != nil || d <= 0 || d > MaxScheduleDuration {
+			errs = append(errs, field.Invalid(fldPath.Child("duration"), s.Duration,
+				fmt.Sprintf("should be a positive duration not longer than %s", MaxScheduleDuration)))
+		}
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("timeZone"), s.TimeZone, "unknown time zone"))
+		}
+
+		if s.WorkersCountMin == nil && s.WorkersCountMax == nil && s.Paused == nil {
+			errs = append(errs, field.Required(fldPath, "workersCountMin, workersCountMax or paused should be set"))
+		}
+		if s.WorkersCountMin != nil && *s.WorkersCountMin < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("workersCountMin"), *s.WorkersCountMin, "can't be negative"))
+		}
+		if s.WorkersCountMax != nil && *s.WorkersCountMax < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child("workersCountMax"), *s.WorkersCountMax, "can't be negative"))
+		}
+
+		min, max := c.WorkersCountMin, c.WorkersCountMax
+		if s.WorkersCountMin != nil {
+			min = *s.WorkersCountMin
+		}
+		if s.WorkersCountMax != nil {
+			max = *s.WorkersCountMax
+		}
+		if max > 0 && min > max {
+			errs = append(errs, field.Invalid(fldPath.Child("workersCountMin"), min,
+				fmt.Sprintf("can't be greater than workersCountMax (%d)", max)))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigScheduled(t *testing.T) {
+	ten, two, paused := 10, 2, true
+	conf := Config{
+		WorkersCountMin: 1,
+		WorkersCountMax: 20,
+		Schedules: []Schedule{
+			{
+				Name:            "business-hours",
+				Cron:            "0 8 * * mon-fri",
+				Duration:        "10h",
+				TimeZone:        "Europe/Berlin",
+				WorkersCountMin: &ten,
+			},
+			{
+				Name:            "nights",
+				Cron:            "0 18 * * *",
+				Duration:        "14h",
+				TimeZone:        "Europe/Berlin",
+				WorkersCountMax: &two,
+				Paused:          &paused,
+			},
+		},
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.Nil(t, err)
+	// Monday
+	monday := time.Date(2018, time.October, 1, 0, 0, 0, 0, berlin)
+
+	tcs := []struct {
+		time             time.Time
+		expectedSchedule string
+		expectedMin      int
+		expectedMax      int
+	}{
+		{
+			time:             monday.Add(9 * time.Hour),
+			expectedSchedule: "business-hours",
+			expectedMin:      10,
+			expectedMax:      20,
+		},
+		{
+			time:             monday.Add(18 * time.Hour),
+			expectedSchedule: "nights",
+			expectedMin:      1,
+			expectedMax:      2,
+		},
+		{
+			// Sunday night schedule ends on Monday morning
+			time:             monday.Add(7*time.Hour + 59*time.Minute),
+			expectedSchedule: "nights",
+			expectedMin:      1,
+			expectedMax:      2,
+		},
+		{
+			time:        monday.AddDate(0, 0, 5).Add(9 * time.Hour),
+			expectedMin: 1,
+			expectedMax: 20,
+		},
+	}
+
+	for i, tc := range tcs {
+		scheduled, s := conf.Scheduled(tc.time)
+		if tc.expectedSchedule == "" {
+			require.Nilf(t, s, "TC#%d", i+1)
+			require.Nilf(t, scheduled.Paused, "TC#%d", i+1)
+		} else {
+			require.NotNilf(t, s, "TC#%d", i+1)
+			require.Equalf(t, tc.expectedSchedule, s.Name, "TC#%d", i+1)
+		}
+		require.Equalf(t, tc.expectedMin, scheduled.WorkersCountMin, "TC#%d", i+1)
+		require.Equalf(t, tc.expectedMax, scheduled.WorkersCountMax, "TC#%d", i+1)
+	}
+}
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"errors"
 	"time"
 )
 
@@ -64,8 +63,113 @@ type Config struct {
 	// DEPRECATED: sg v1 has no support.
 	SupergiantV1Config *SupergiantV1UserdataVars `json:"supergiantV1Config,omitempty"`
 	// Strategy is a way capacity determines a machine to create for unscheduled pods. Capacity recognizes 'bigBox',
-	// 'smallCPUBox' and 'smallMemBox' ones. The 'bigBox' one is used by default.
+	// 'smallCPUBox', 'smallMemBox', 'leastWaste', 'mostPods', 'cheapestPerUnit' and 'priority' ones. The 'bigBox' one is used by default.
 	Strategy ScaleUpStrategy `json:"strategy"`
+	// MachineTypePriorities is an ordered list of machine type regular expressions for the 'priority' strategy,
+	// e.g. ['^m5\\.', '^m4\\.'].
+	MachineTypePriorities []string `json:"machineTypePriorities,omitempty"`
+	// DefaultMachineType is used to create workers when the cluster has less than WorkersCountMin
+	// of them. The cheapest one of MachineTypes is used by default.
+	DefaultMachineType string `json:"defaultMachineType,omitempty"`
+	// ScaleUpPriorityCutoff is a minimal pod priority to trigger scale up. Pods with a lower priority
+	// are expected to wait or to be preempted, they aren't counted as a load on scale down either.
+	ScaleUpPriorityCutoff *int32 `json:"scaleUpPriorityCutoff,omitempty"`
+	// IgnoredPodNamespaces and IgnoredPodSelectors are rules for unscheduled pods that shouldn't
+	// trigger scale up, e.g. CI sandboxes. Selectors use the label selector syntax, e.g. 'app=ci,tier!=prod',
+	// a pod is ignored if it matches any of them.
+	IgnoredPodNamespaces []string `json:"ignoredPodNamespaces,omitempty"`
+	IgnoredPodSelectors  []string `json:"ignoredPodSelectors,omitempty"`
+	// Headroom is spare capacity kept in the cluster for sudden load.
+	Headroom *Headroom `json:"headroom,omitempty"`
+	// NodeReserved is used to estimate resources available for pods on machine types
+	// without ready nodes, allocatable resources of the nodes are used otherwise.
+	NodeReserved *NodeReserved `json:"nodeReserved,omitempty"`
+	// MaxPodsPerNode is a kubelet pods limit (the --max-pods flag), it's used for machine types
+	// without ready nodes along with provider limits, e.g. AWS network interfaces.
+	MaxPodsPerNode int `json:"maxPodsPerNode,omitempty"`
+	// MachineTypeResources are extended resources advertised by nodes of machine types, e.g.
+	// {"m5.large": {"example.com/fpga": "1"}}. Allocatable resources of ready nodes are used if they exist.
+	MachineTypeResources map[string]map[string]string `json:"machineTypeResources,omitempty"`
+	// Schedules override workers count limits and pausing during time windows. The first
+	// active schedule is used.
+	Schedules []Schedule `json:"schedules,omitempty"`
+}
+
+// Headroom is a room for a number of virtual pods of the given size. Workers are created
+// if they don't fit on ready nodes, as if they were unscheduled pods.
+type Headroom struct {
+	// CPU and Memory are virtual pod requests, e.g. '500m' and '1Gi'.
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+	// Pods is a number of virtual pods, one is used by default.
+	Pods int `json:"pods,omitempty"`
+}
+
+// NodeReserved is a part of machine resources unavailable for pods: kube-reserved, system-reserved
+// and eviction thresholds in total.
+type NodeReserved struct {
+	// CPU and Memory are reserved resources, e.g. '100m' and '500Mi'.
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// Schedule is a set of config overrides applied during time windows. A window starts
+// at the time matching the cron expression and lasts for the duration.
+type Schedule struct {
+	Name string `json:"name,omitempty"`
+	// Cron is a 5-field cron expression for window starts, e.g. '0 8 * * mon-fri'.
+	Cron string `json:"cron"`
+	// Duration is a window length, e.g. '10h'. It can't be longer than a week.
+	Duration string `json:"duration"`
+	// TimeZone is an IANA time zone name for the cron expression, e.g. 'Europe/Berlin'.
+	// UTC is used by default.
+	TimeZone        string `json:"timeZone,omitempty"`
+	WorkersCountMin *int   `json:"workersCountMin,omitempty"`
+	WorkersCountMax *int   `json:"workersCountMax,omitempty"`
+	Paused          *bool  `json:"paused,omitempty"`
+}
+
+// Status is an observed scaler state.
+type Status struct {
+	// Workers is a number of pending and running workers, masters aren't counted.
+	Workers int `json:"workers"`
+	// ReadyWorkers is a number of workers with ready nodes.
+	ReadyWorkers int `json:"readyWorkers"`
+	// WorkersCountMin and WorkersCountMax are the limits in effect, schedules are taken into account.
+	WorkersCountMin int `json:"workersCountMin"`
+	WorkersCountMax int `json:"workersCountMax"`
+	// Shortfall is a number of workers missing to meet the WorkersCountMin.
+	Shortfall int `json:"shortfall"`
+	// Schedule is a name (or a cron expression) of the active schedule.
+	Schedule string `json:"schedule,omitempty"`
+	// LastError is the last config or scan error.
+	LastError string `json:"lastError,omitempty"`
+	// LastScanTime is a time of the last scan, it's empty until the service is configured.
+	LastScanTime *time.Time `json:"lastScanTime,omitempty"`
+	// Backoff lists machine types that aren't used to scale up after failures to create workers.
+	Backoff []MachineTypeBackoff `json:"backoff,omitempty"`
+}
+
+// MachineTypeBackoff is a machine type that has failed to create workers recently.
+type MachineTypeBackoff struct {
+	MachineType string `json:"machineType"`
+	// Failures is a number of consecutive failures, the backoff is doubled on each one.
+	Failures int `json:"failures"`
+	// Until is a time the machine type is tried again.
+	Until time.Time `json:"until"`
+	// LastError is the last error of the provider.
+	LastError string `json:"lastError"`
+}
+
+// ConfigRevision is a config applied at some point in time.
+type ConfigRevision struct {
+	// Revision is a sequence number of the revision.
+	Revision int `json:"revision"`
+	// Author is a name of the user who applied the config, it's empty for external changes.
+	Author string `json:"author,omitempty"`
+	// Timestamp is a time when the config was applied.
+	Timestamp time.Time `json:"timestamp"`
+	Config    Config    `json:"config"`
 }
 
 type ScaleUpStrategy string
@@ -80,26 +184,29 @@ var (
 	// SmallMemBox is a strategy for capacity. It's used to determine a machine type for the smallest pod at once.
 	// The one with the lower price and higher amount of memory and CPU will be created. Has priority by memory.
 	SmallMemBox ScaleUpStrategy = "smallMemBox"
+	// LeastWaste is a strategy that picks a machine type with the least unused CPU and memory share
+	// after placing unscheduled pods on it.
+	LeastWaste ScaleUpStrategy = "leastWaste"
+	// MostPods is a strategy that picks a machine type able to run most of the unscheduled pods.
+	MostPods ScaleUpStrategy = "mostPods"
+	// CheapestPerUnit is a strategy that picks a machine type with the lowest price per vCPU and GiB
+	// of the ones able to run unscheduled pods.
+	CheapestPerUnit ScaleUpStrategy = "cheapestPerUnit"
+	// PriorityList is a strategy that picks machine types in the order of MachineTypePriorities patterns.
+	// The bigBox one is used for types matching the same pattern or if none of them matches.
+	PriorityList ScaleUpStrategy = "priority"
 )
 
+// ScaleUpStrategies is a list of supported strategies.
+var ScaleUpStrategies = []ScaleUpStrategy{BigBox, SmallCPUBox, SmallMemBox, LeastWaste, MostPods, CheapestPerUnit, PriorityList}
+
 type SupergiantV1UserdataVars struct {
 	MasterPrivateAddr string `json:"masterPrivateAddr"`
 	KubeAPIHost       string `json:"kubeAPIHost"`
 	KubeAPIPort       string `json:"kubeAPIPort"`
 	KubeAPIUser       string `json:"kubeAPIUser"`
-	KubeAPIPassword   string `json:"kubeAPIPassword"`
+	KubeAPIPassword   string `json:"kubeAPIPassword" sensitive:"true"`
 	SSHPubKey         string `json:"sshPubKey"`
 	KubeVersion       string `json:"-"`
 	ProviderName      string `json:"-"`
 }
-
-func (c Config) Validate() error {
-	// TODO: pass it with a pointer or use the ConfigRequest struct for patches.
-	if c.WorkersCountMin < 0 {
-		return errors.New("WorkersCountMin can't be negative")
-	}
-	if c.WorkersCountMax < 0 {
-		return errors.New("WorkersCountMax can't be negative")
-	}
-	return nil
-}
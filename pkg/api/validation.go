@@ -0,0 +1,233 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// FieldError describes an invalid config field.
+type FieldError struct {
+	// Field is a path to the field, e.g. 'provider.awsRegion' or 'machineTypes[0]'.
+	Field string `json:"field"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// ValidationError is returned for configs with invalid fields.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// NewValidationError returns nil for an empty list.
+func NewValidationError(errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	out := &ValidationError{
+		Errors: make([]FieldError, 0, len(errs)),
+	}
+	for _, err := range errs {
+		out.Errors = append(out.Errors, FieldError{
+			Field:   err.Field,
+			Message: err.ErrorBody(),
+		})
+	}
+	return out
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", err.Field, err.Message))
+	}
+	return "invalid config: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks provider independent config parameters.
+func (c Config) Validate() error {
+	return NewValidationError(c.ValidateFields())
+}
+
+// ValidateFields checks provider independent config parameters and returns
+// a list of invalid fields.
+func (c Config) ValidateFields() field.ErrorList {
+	errs := field.ErrorList{}
+
+	if strings.TrimSpace(c.ClusterName) == "" {
+		errs = append(errs, field.Required(field.NewPath("clusterName"), ""))
+	}
+	if c.WorkersCountMin < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("workersCountMin"), c.WorkersCountMin, "can't be negative"))
+	}
+	if c.WorkersCountMax < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("workersCountMax"), c.WorkersCountMax, "can't be negative"))
+	}
+	if c.WorkersCountMax > 0 && c.WorkersCountMin > c.WorkersCountMax {
+		errs = append(errs, field.Invalid(field.NewPath("workersCountMin"), c.WorkersCountMin,
+			fmt.Sprintf("can't be greater than workersCountMax (%d)", c.WorkersCountMax)))
+	}
+	if c.WorkersLifespanMinutes < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("workersLifespanMinutes"), c.WorkersLifespanMinutes, "can't be negative"))
+	}
+	errs = append(errs, validateDuration(field.NewPath("scanInterval"), c.ScanInterval)...)
+	errs = append(errs, validateDuration(field.NewPath("maxMachineProvisionTime"), c.MaxMachineProvisionTime)...)
+
+	if c.Strategy != "" && !isKnownStrategy(c.Strategy) {
+		valid := make([]string, 0, len(ScaleUpStrategies))
+		for _, s := range ScaleUpStrategies {
+			valid = append(valid, string(s))
+		}
+		errs = append(errs, field.NotSupported(field.NewPath("strategy"), c.Strategy, valid))
+	}
+	if c.Strategy == PriorityList && len(c.MachineTypePriorities) == 0 {
+		errs = append(errs, field.Required(field.NewPath("machineTypePriorities"), "should be set for the priority strategy"))
+	}
+	for i, p := range c.MachineTypePriorities {
+		if _, err := regexp.Compile(p); err != nil || strings.TrimSpace(p) == "" {
+			errs = append(errs, field.Invalid(field.NewPath("machineTypePriorities").Index(i), p, "should be a regular expression, e.g. '^m5\\.'"))
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, name := range c.MachineTypes {
+		if seen[name] {
+			errs = append(errs, field.Duplicate(field.NewPath("machineTypes").Index(i), name))
+		}
+		seen[name] = true
+	}
+	if c.DefaultMachineType != "" && !seen[c.DefaultMachineType] {
+		errs = append(errs, field.NotSupported(field.NewPath("defaultMachineType"), c.DefaultMachineType, c.MachineTypes))
+	}
+
+	for i, ns := range c.IgnoredPodNamespaces {
+		if strings.TrimSpace(ns) == "" {
+			errs = append(errs, field.Required(field.NewPath("ignoredPodNamespaces").Index(i), ""))
+		}
+	}
+	for i, sel := range c.IgnoredPodSelectors {
+		if _, err := labels.Parse(sel); err != nil || strings.TrimSpace(sel) == "" {
+			errs = append(errs, field.Invalid(field.NewPath("ignoredPodSelectors").Index(i), sel, "should be a label selector, e.g. 'app=ci'"))
+		}
+	}
+	errs = append(errs, c.validateHeadroom()...)
+	errs = append(errs, c.validateNodeReserved()...)
+	errs = append(errs, c.validateMachineTypeResources(seen)...)
+	if c.MaxPodsPerNode < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("maxPodsPerNode"), c.MaxPodsPerNode, "can't be negative"))
+	}
+	errs = append(errs, c.validateSchedules()...)
+
+	if c.SupergiantV1Config == nil && strings.TrimSpace(c.Userdata) == "" {
+		errs = append(errs, field.Required(field.NewPath("userdata"), "userdata or supergiantV1Config should be provided"))
+	}
+
+	return errs
+}
+
+func (c Config) validateNodeReserved() field.ErrorList {
+	if c.NodeReserved == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	fldPath := field.NewPath("nodeReserved")
+	for _, r := range []struct{ name, val string }{{"cpu", c.NodeReserved.CPU}, {"memory", c.NodeReserved.Memory}} {
+		if r.val == "" {
+			continue
+		}
+		if q, err := resource.ParseQuantity(r.val); err != nil || q.Sign() < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(r.name), r.val, "should be a non-negative quantity, e.g. '100m' or '500Mi'"))
+		}
+	}
+	return errs
+}
+
+func (c Config) validateMachineTypeResources(allowed map[string]bool) field.ErrorList {
+	errs := field.ErrorList{}
+	fldPath := field.NewPath("machineTypeResources")
+	mtypes := make([]string, 0, len(c.MachineTypeResources))
+	for mtype := range c.MachineTypeResources {
+		mtypes = append(mtypes, mtype)
+	}
+	sort.Strings(mtypes)
+	for _, mtype := range mtypes {
+		if !allowed[mtype] {
+			errs = append(errs, field.NotSupported(fldPath.Key(mtype), mtype, c.MachineTypes))
+			continue
+		}
+		resources := c.MachineTypeResources[mtype]
+		names := make([]string, 0, len(resources))
+		for name := range resources {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !strings.Contains(name, "/") || strings.Contains(name, "kubernetes.io/") {
+				errs = append(errs, field.Invalid(fldPath.Key(mtype).Key(name), name, "should be an extended resource name, e.g. 'example.com/fpga'"))
+				continue
+			}
+			if q, err := resource.ParseQuantity(resources[name]); err != nil || q.Sign() < 0 {
+				errs = append(errs, field.Invalid(fldPath.Key(mtype).Key(name), resources[name], "should be a non-negative quantity, e.g. '1'"))
+			}
+		}
+	}
+	return errs
+}
+
+func (c Config) validateHeadroom() field.ErrorList {
+	if c.Headroom == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	fldPath := field.NewPath("headroom")
+	empty := true
+	for _, r := range []struct{ name, val string }{{"cpu", c.Headroom.CPU}, {"memory", c.Headroom.Memory}} {
+		name, val := r.name, r.val
+		if val == "" {
+			continue
+		}
+		if q, err := resource.ParseQuantity(val); err != nil || q.Sign() < 0 {
+			errs = append(errs, field.Invalid(fldPath.Child(name), val, "should be a non-negative quantity, e.g. '500m' or '1Gi'"))
+		} else if q.Sign() > 0 {
+			empty = false
+		}
+	}
+	if empty && len(errs) == 0 {
+		errs = append(errs, field.Required(fldPath, "cpu or memory should be set"))
+	}
+	if c.Headroom.Pods < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("pods"), c.Headroom.Pods, "can't be negative"))
+	}
+	return errs
+}
+
+func validateDuration(fldPath *field.Path, val string) field.ErrorList {
+	if val == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return field.ErrorList{field.Invalid(fldPath, val, "should be a duration, e.g. '20s' or '10m'")}
+	}
+	if d <= 0 {
+		return field.ErrorList{field.Invalid(fldPath, val, "should be positive")}
+	}
+	return nil
+}
+
+func isKnownStrategy(s ScaleUpStrategy) bool {
+	for i := range ScaleUpStrategies {
+		if ScaleUpStrategies[i] == s {
+			return true
+		}
+	}
+	return false
+}
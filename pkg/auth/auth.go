@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Supported authentication modes:
+const (
+	ModeToken       = "token"
+	ModeTokenReview = "tokenreview"
+	ModeX509        = "x509"
+)
+
+// Package specific errors:
+var (
+	ErrUnknownMode  = errors.New("unknown authentication mode")
+	ErrUnknownRole  = errors.New("unknown role")
+	ErrInvalidToken = errors.New("invalid bearer token")
+)
+
+// Role defines a set of actions a user is allowed to perform.
+type Role string
+
+const (
+	// RoleNone is assigned to authenticated users without any granted role.
+	RoleNone Role = ""
+	// RoleReadOnly allows to view workers, machine types and the config.
+	RoleReadOnly Role = "read-only"
+	// RoleOperator additionally allows to create, reserve and delete workers.
+	RoleOperator Role = "operator"
+	// RoleAdmin additionally allows to change the config.
+	RoleAdmin Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleNone:     0,
+	RoleReadOnly: 1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole converts a string representation to the Role.
+func ParseRole(s string) (Role, error) {
+	r := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := roleLevels[r]; !ok || r == RoleNone {
+		return RoleNone, errors.Wrap(ErrUnknownRole, s)
+	}
+	return r, nil
+}
+
+// Allows returns true if the role permits actions that require the provided one.
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// User represents an authenticated API client.
+type User struct {
+	Name   string
+	Groups []string
+	Role   Role
+}
+
+// Authenticator verifies request credentials. It returns false if the request
+// doesn't contain credentials it knows about, so the next one could be tried.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*User, bool, error)
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of the context that holds the user.
+func WithUser(ctx context.Context, u *User) context.Context {
+	return context.WithValue(ctx, userKey{}, u)
+}
+
+// UserFrom returns the user stored in the context, if any.
+func UserFrom(ctx context.Context) (*User, bool) {
+	u, ok := ctx.Value(userKey{}).(*User)
+	return u, ok
+}
+
+// GroupRoles maps user groups to roles. The most permissive one is used if
+// a user is a member of several groups.
+type GroupRoles map[string]Role
+
+// RoleFor returns the most permissive role granted to any of the groups.
+func (g GroupRoles) RoleFor(groups []string) Role {
+	role := RoleNone
+	for _, group := range groups {
+		if r, ok := g[group]; ok && r.Allows(role) {
+			role = r
+		}
+	}
+	return role
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	h := strings.TrimSpace(r.Header.Get("Authorization"))
+	parts := strings.SplitN(h, " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return "", false
+	}
+	token := strings.TrimSpace(parts[1])
+	return token, token != ""
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+// Config holds authentication parameters. Authentication is disabled if no modes are provided.
+type Config struct {
+	// Modes is a list of enabled authenticators: 'token', 'tokenreview' or 'x509'.
+	Modes []string
+	// TokenSecret parameters are used by the 'token' authenticator.
+	TokenSecretName      string
+	TokenSecretNamespace string
+	TokenSecretKey       string
+	// GroupRoles grants roles to groups of users authenticated by the 'tokenreview' and 'x509' ones.
+	GroupRoles GroupRoles
+}
+
+// Enabled returns true if the mode is in the list of enabled authenticators.
+func (c Config) Enabled(mode string) bool {
+	for _, m := range c.Modes {
+		if strings.ToLower(strings.TrimSpace(m)) == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// New builds authenticators for the enabled modes.
+func New(conf Config, kclient corev1client.CoreV1Interface) ([]Authenticator, error) {
+	authenticators := make([]Authenticator, 0, len(conf.Modes))
+	for _, mode := range conf.Modes {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case "":
+			continue
+		case ModeToken:
+			a, err := NewTokenAuthenticator(conf.TokenSecretName, conf.TokenSecretNamespace, conf.TokenSecretKey, kclient)
+			if err != nil {
+				return nil, errors.Wrap(err, "setup token authenticator")
+			}
+			authenticators = append(authenticators, a)
+		case ModeTokenReview:
+			a, err := NewTokenReviewAuthenticator(kclient.RESTClient(), conf.GroupRoles)
+			if err != nil {
+				return nil, errors.Wrap(err, "setup tokenreview authenticator")
+			}
+			authenticators = append(authenticators, a)
+		case ModeX509:
+			authenticators = append(authenticators, NewX509Authenticator(conf.GroupRoles))
+		default:
+			return nil, errors.Wrap(ErrUnknownMode, mode)
+		}
+	}
+	return authenticators, nil
+}
+
+// Middleware authenticates requests and checks the user role allows the requested action.
+// All requests are permitted if there are no authenticators.
+func Middleware(authenticators []Authenticator) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if len(authenticators) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := authenticate(authenticators, r)
+			if user == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="capacity"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if required := RequiredRole(r); !user.Role.Allows(required) {
+				log.Warnf("auth: %s %s: user %q with %q role isn't allowed, requires %q",
+					r.Method, r.URL.Path, user.Name, user.Role, required)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+		})
+	}
+}
+
+func authenticate(authenticators []Authenticator, r *http.Request) *User {
+	for _, a := range authenticators {
+		user, ok, err := a.Authenticate(r)
+		if err != nil {
+			log.Errorf("auth: %s %s: %v", r.Method, r.URL.Path, err)
+			continue
+		}
+		if ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// RequiredRole returns a minimal role needed to perform the request:
+//   - read requests are allowed for read-only users;
+//   - config changes are allowed for admins only;
+//   - other changes (workers management) are allowed for operators.
+func RequiredRole(r *http.Request) Role {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return RoleReadOnly
+	}
+
+	for _, segment := range strings.Split(r.URL.Path, "/") {
+		if segment == "config" {
+			return RoleAdmin
+		}
+	}
+	return RoleOperator
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+type fakeAuthenticator map[string]*User
+
+func (a fakeAuthenticator) Authenticate(r *http.Request) (*User, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, false, nil
+	}
+	u, ok := a[token]
+	return u, ok, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticators := []Authenticator{fakeAuthenticator{
+		"viewer":   {Name: "viewer", Role: RoleReadOnly},
+		"operator": {Name: "operator", Role: RoleOperator},
+		"admin":    {Name: "admin", Role: RoleAdmin},
+		"nobody":   {Name: "nobody"},
+	}}
+
+	tcs := []struct {
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{http.MethodGet, "/api/v1/workers", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/workers", "unknown", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/workers", "nobody", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/workers", "viewer", http.StatusOK},
+		{http.MethodGet, "/api/v1/config", "viewer", http.StatusOK},
+		{http.MethodDelete, "/api/v1/workers/i-1", "viewer", http.StatusForbidden},
+		{http.MethodDelete, "/api/v1/workers/i-1", "operator", http.StatusOK},
+		{http.MethodPatch, "/api/v1/config", "operator", http.StatusForbidden},
+		{http.MethodPatch, "/api/v1/config", "admin", http.StatusOK},
+		{http.MethodPost, "/api/v1/workers", "admin", http.StatusOK},
+	}
+
+	for i, tc := range tcs {
+		var user *User
+		h := Middleware(authenticators)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ = UserFrom(r.Context())
+		}))
+
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		require.Equalf(t, tc.expectedStatus, rr.Code, "TC#%d", i+1)
+		if tc.expectedStatus == http.StatusOK {
+			require.NotNilf(t, user, "TC#%d", i+1)
+			require.Equalf(t, tc.token, user.Name, "TC#%d", i+1)
+		}
+	}
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	var called bool
+	h := Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/config", nil))
+	require.True(t, called)
+}
+
+func TestConfigEnabled(t *testing.T) {
+	conf := Config{Modes: []string{"token", " X509 "}}
+	require.True(t, conf.Enabled(ModeX509))
+	require.True(t, conf.Enabled(ModeToken))
+	require.False(t, conf.Enabled(ModeTokenReview))
+}
+
+func TestParseTokens(t *testing.T) {
+	entries, err := parseTokens("# token,user,role\nt1,alice,admin\nt2, bob, read-only\n")
+	require.Nil(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, RoleAdmin, entries[0].user.Role)
+	require.Equal(t, "bob", entries[1].user.Name)
+	require.Equal(t, RoleReadOnly, entries[1].user.Role)
+
+	_, err = parseTokens("t1,alice,root")
+	require.NotNil(t, err)
+}
+
+func TestGroupRoles(t *testing.T) {
+	roles := GroupRoles{
+		"devs": RoleReadOnly,
+		"ops":  RoleOperator,
+	}
+	require.Equal(t, RoleNone, roles.RoleFor([]string{"guests"}))
+	require.Equal(t, RoleOperator, roles.RoleFor([]string{"ops", "devs"}))
+	require.Equal(t, RoleOperator, roles.RoleFor([]string{"devs", "ops"}))
+}
+
+// fakeSecrets returns the Secret from its data, other methods of the interface aren't implemented.
+type fakeSecrets struct {
+	v1.SecretInterface
+	data map[string][]byte
+	err  error
+}
+
+func (s *fakeSecrets) Secrets(namespace string) v1.SecretInterface {
+	return s
+}
+
+func (s *fakeSecrets) Get(name string, options metav1.GetOptions) (*corev1.Secret, error) {
+	return &corev1.Secret{Data: s.data}, s.err
+}
+
+func TestTokenAuthenticatorRevocation(t *testing.T) {
+	secrets := &fakeSecrets{data: map[string][]byte{
+		DefaultTokenSecretKey: []byte("t1,alice,admin\nt2,bob,operator\n"),
+	}}
+	a, err := NewTokenAuthenticator("tokens", "capacity", "", secrets)
+	require.Nil(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workers", nil)
+	req.Header.Set("Authorization", "Bearer t1")
+	u, ok, err := a.Authenticate(req)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, RoleAdmin, u.Role)
+
+	// cached tokens are used until the refresh interval passes
+	secrets.data[DefaultTokenSecretKey] = []byte("t2,bob,read-only\n")
+	_, ok, err = a.Authenticate(req)
+	require.Nil(t, err)
+	require.True(t, ok)
+
+	a.mu.Lock()
+	a.nextRefresh = time.Now().Add(-time.Second)
+	a.mu.Unlock()
+
+	_, ok, err = a.Authenticate(req)
+	require.Nil(t, err)
+	require.False(t, ok)
+
+	req.Header.Set("Authorization", "Bearer t2")
+	u, ok, err = a.Authenticate(req)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, RoleReadOnly, u.Role)
+
+	// the last read tokens are used if the secret can't be read
+	secrets.err = errors.New("apiserver is unavailable")
+	a.mu.Lock()
+	a.nextRefresh = time.Now().Add(-time.Second)
+	a.mu.Unlock()
+
+	u, ok, err = a.Authenticate(req)
+	require.Nil(t, err)
+	require.True(t, ok)
+	require.Equal(t, RoleReadOnly, u.Role)
+	require.True(t, a.nextRefresh.Before(time.Now().Add(tokenRetryInterval+time.Second)))
+}
+
+func TestTokenReviewCache(t *testing.T) {
+	a := &TokenReviewAuthenticator{cache: make(map[[sha256.Size]byte]reviewResult)}
+	now := time.Now()
+
+	user := &User{Name: "alice"}
+	a.store(sha256.Sum256([]byte("valid")), user, now)
+	for i := 0; i < 2*tokenReviewCacheSize; i++ {
+		a.store(sha256.Sum256([]byte(strconv.Itoa(i))), nil, now.Add(time.Duration(i)*time.Millisecond))
+	}
+	require.Len(t, a.cache, tokenReviewCacheSize)
+
+	// negative results expire earlier, so they are evicted first
+	u, found := a.cached(sha256.Sum256([]byte("valid")))
+	require.True(t, found)
+	require.Equal(t, user, u)
+	_, found = a.cached(sha256.Sum256([]byte("0")))
+	require.False(t, found)
+	u, found = a.cached(sha256.Sum256([]byte(strconv.Itoa(2*tokenReviewCacheSize - 1))))
+	require.True(t, found)
+	require.Nil(t, u)
+}
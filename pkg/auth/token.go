@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+const (
+	// DefaultTokenSecretKey is a Secret data key with a list of static tokens.
+	DefaultTokenSecretKey = "tokens.csv"
+
+	tokenRefreshInterval = 30 * time.Second
+	// tokenRetryInterval is a delay before the next attempt if the Secret can't be read,
+	// the last read tokens are used meanwhile.
+	tokenRetryInterval = 5 * time.Second
+)
+
+type tokenEntry struct {
+	token string
+	user  User
+}
+
+// TokenAuthenticator authenticates requests with static bearer tokens stored
+// in a kubernetes Secret. Every line of the Secret data key has the
+// 'token,user,role' format. The Secret is re-read periodically, so tokens
+// could be rotated without restart.
+type TokenAuthenticator struct {
+	name      string
+	namespace string
+	key       string
+	client    v1.SecretsGetter
+
+	mu      sync.RWMutex
+	entries []tokenEntry
+	// nextRefresh is a time the Secret should be re-read at
+	nextRefresh time.Time
+	// refreshing is set while a request re-reads the Secret, others use the current tokens
+	refreshing bool
+}
+
+// NewTokenAuthenticator creates a TokenAuthenticator and loads tokens from the Secret.
+func NewTokenAuthenticator(name, namespace, key string, client v1.SecretsGetter) (*TokenAuthenticator, error) {
+	if name == "" || namespace == "" {
+		return nil, errors.New("token secret name and namespace should be provided")
+	}
+	if client == nil {
+		return nil, errors.New("secrets client should be provided")
+	}
+	if key == "" {
+		key = DefaultTokenSecretKey
+	}
+
+	a := &TokenAuthenticator{
+		name:      name,
+		namespace: namespace,
+		key:       key,
+		client:    client,
+	}
+	if err := a.refresh(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Authenticate looks up the request bearer token.
+func (a *TokenAuthenticator) Authenticate(r *http.Request) (*User, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, false, nil
+	}
+
+	// tokens may have been added, removed or changed their roles
+	if a.startRefresh() {
+		err := a.refresh()
+		a.finishRefresh(err)
+		if err != nil {
+			log.Errorf("auth: %v: use the last read tokens", err)
+		}
+	}
+
+	if u := a.lookup(token); u != nil {
+		return u, true, nil
+	}
+	return nil, false, nil
+}
+
+func (a *TokenAuthenticator) lookup(token string) *User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for i := range a.entries {
+		if subtle.ConstantTimeCompare([]byte(a.entries[i].token), []byte(token)) == 1 {
+			u := a.entries[i].user
+			return &u
+		}
+	}
+	return nil
+}
+
+// startRefresh reports whether the Secret should be re-read by the caller, only one caller
+// does this at a time.
+func (a *TokenAuthenticator) startRefresh() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.refreshing || time.Now().Before(a.nextRefresh) {
+		return false
+	}
+	a.refreshing = true
+	return true
+}
+
+func (a *TokenAuthenticator) finishRefresh(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.refreshing = false
+	if err != nil {
+		a.nextRefresh = time.Now().Add(tokenRetryInterval)
+	}
+}
+
+func (a *TokenAuthenticator) refresh() error {
+	secret, err := a.client.Secrets(a.namespace).Get(a.name, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "get %s/%s secret", a.namespace, a.name)
+	}
+
+	entries, err := parseTokens(string(secret.Data[a.key]))
+	if err != nil {
+		return errors.Wrapf(err, "parse %s key of %s/%s secret", a.key, a.namespace, a.name)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.entries = entries
+	a.nextRefresh = time.Now().Add(tokenRefreshInterval)
+	return nil
+}
+
+func parseTokens(data string) ([]tokenEntry, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]tokenEntry, 0, len(records))
+	for _, rec := range records {
+		role, err := ParseRole(rec[2])
+		if err != nil {
+			return nil, errors.Wrapf(err, "user %s", rec[1])
+		}
+		if rec[0] == "" {
+			return nil, errors.Wrapf(ErrInvalidToken, "user %s", rec[1])
+		}
+		entries = append(entries, tokenEntry{
+			token: rec[0],
+			user: User{
+				Name: rec[1],
+				Role: role,
+			},
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	tokenReviewPath     = "/apis/authentication.k8s.io/v1/tokenreviews"
+	tokenReviewCacheTTL = time.Minute
+	// unauthenticated tokens are cached for a shorter time
+	tokenReviewNegativeTTL = 10 * time.Second
+	// tokenReviewCacheSize limits a number of cached tokens, e.g. if random ones are sent
+	tokenReviewCacheSize = 1024
+)
+
+type reviewResult struct {
+	user    *User
+	expires time.Time
+}
+
+// TokenReviewAuthenticator authenticates bearer tokens (e.g. serviceaccount ones)
+// using the kubernetes TokenReview API. Roles are granted by user groups.
+type TokenReviewAuthenticator struct {
+	client rest.Interface
+	roles  GroupRoles
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]reviewResult
+}
+
+// NewTokenReviewAuthenticator creates a TokenReviewAuthenticator.
+func NewTokenReviewAuthenticator(client rest.Interface, roles GroupRoles) (*TokenReviewAuthenticator, error) {
+	if client == nil {
+		return nil, errors.New("kubernetes rest client should be provided")
+	}
+	return &TokenReviewAuthenticator{
+		client: client,
+		roles:  roles,
+		cache:  make(map[[sha256.Size]byte]reviewResult),
+	}, nil
+}
+
+// Authenticate reviews the request bearer token.
+func (a *TokenReviewAuthenticator) Authenticate(r *http.Request) (*User, bool, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return nil, false, nil
+	}
+
+	key := sha256.Sum256([]byte(token))
+	if u, found := a.cached(key); found {
+		return u, u != nil, nil
+	}
+
+	u, err := a.review(token)
+	if err != nil {
+		return nil, false, err
+	}
+
+	a.store(key, u, time.Now())
+	return u, u != nil, nil
+}
+
+// store caches the review result, expired entries are removed once the cache is full,
+// the ones expiring first are evicted if it's still full.
+func (a *TokenReviewAuthenticator) store(key [sha256.Size]byte, u *User, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.cache) >= tokenReviewCacheSize {
+		for k, res := range a.cache {
+			if now.After(res.expires) {
+				delete(a.cache, k)
+			}
+		}
+	}
+	for len(a.cache) >= tokenReviewCacheSize {
+		var oldest [sha256.Size]byte
+		var oldestExpires time.Time
+		for k, res := range a.cache {
+			if oldestExpires.IsZero() || res.expires.Before(oldestExpires) {
+				oldest, oldestExpires = k, res.expires
+			}
+		}
+		delete(a.cache, oldest)
+	}
+
+	ttl := tokenReviewCacheTTL
+	if u == nil {
+		ttl = tokenReviewNegativeTTL
+	}
+	a.cache[key] = reviewResult{user: u, expires: now.Add(ttl)}
+}
+
+func (a *TokenReviewAuthenticator) cached(key [sha256.Size]byte) (*User, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	res, ok := a.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(res.expires) {
+		delete(a.cache, key)
+		return nil, false
+	}
+	return res.user, true
+}
+
+func (a *TokenReviewAuthenticator) review(token string) (*User, error) {
+	body, err := json.Marshal(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token: token,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := a.client.Post().AbsPath(tokenReviewPath).Body(body).Do().Raw()
+	if err != nil {
+		return nil, errors.Wrap(err, "create token review")
+	}
+
+	review := &authenticationv1.TokenReview{}
+	if err = json.Unmarshal(raw, review); err != nil {
+		return nil, errors.Wrap(err, "decode token review")
+	}
+	if !review.Status.Authenticated {
+		return nil, nil
+	}
+
+	return &User{
+		Name:   review.Status.User.Username,
+		Groups: review.Status.User.Groups,
+		Role:   a.roles.RoleFor(review.Status.User.Groups),
+	}, nil
+}
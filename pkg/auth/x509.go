@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+)
+
+// X509Authenticator authenticates requests by verified TLS client certificates.
+// The certificate common name is used as a user name and organizations as groups.
+type X509Authenticator struct {
+	roles GroupRoles
+}
+
+// NewX509Authenticator creates a X509Authenticator.
+func NewX509Authenticator(roles GroupRoles) *X509Authenticator {
+	return &X509Authenticator{
+		roles: roles,
+	}
+}
+
+// Authenticate checks the request client certificate. The certificate chain is expected
+// to be verified during TLS handshake.
+func (a *X509Authenticator) Authenticate(r *http.Request) (*User, bool, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil, false, nil
+	}
+
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, false, nil
+	}
+
+	return &User{
+		Name:   cert.Subject.CommonName,
+		Groups: cert.Subject.Organization,
+		Role:   a.roles.RoleFor(cert.Subject.Organization),
+	}, true, nil
+}
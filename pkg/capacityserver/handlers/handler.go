@@ -11,7 +11,7 @@ import (
 	"github.com/supergiant/capacity/pkg/kubescaler"
 )
 
-func RegisterRouter(ks *kubescaler.Kubescaler, handler *v1.HandlerV1) (*mux.Router, error) {
+func RegisterRouter(ks *kubescaler.Kubescaler, handler *v1.HandlerV1, middlewares ...mux.MiddlewareFunc) (*mux.Router, error) {
 	r := mux.NewRouter()
 
 	r.Path("/version").Methods(http.MethodGet).HandlerFunc(version.Handler)
@@ -21,6 +21,7 @@ func RegisterRouter(ks *kubescaler.Kubescaler, handler *v1.HandlerV1) (*mux.Rout
 	apiv1.Use(
 		mux.MiddlewareFunc(setContentType),
 	)
+	apiv1.Use(middlewares...)
 
 	return r, nil
 }
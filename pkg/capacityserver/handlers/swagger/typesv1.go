@@ -12,6 +12,27 @@ type configResponse struct {
 	Config *api.Config `json:"config"`
 }
 
+// validationErrorResponse contains a list of invalid config fields.
+// swagger:response validationErrorResponse
+type validationErrorResponse struct {
+	// in:body
+	Errors *api.ValidationError `json:"errors"`
+}
+
+// configRevisionsResponse contains stored config revisions.
+// swagger:response configRevisionsResponse
+type configRevisionsResponse struct {
+	// in:body
+	Revisions []api.ConfigRevision `json:"revisions"`
+}
+
+// statusResponse contains the observed scaler state.
+// swagger:response statusResponse
+type statusResponse struct {
+	// in:body
+	Status *api.Status `json:"status"`
+}
+
 // machineTypesListResponse contains a list of workers.
 // swagger:response machineTypesListResponse
 type machineTypesListResponse struct {
@@ -40,3 +61,11 @@ type machineIDParam struct {
 	// required: true
 	MachineID string `json:"machineID"`
 }
+
+// revisionParam is used to identify a config revision.
+// swagger:parameters rollbackConfig
+type revisionParam struct {
+	// in:path
+	// required: true
+	Revision int `json:"revision"`
+}
@@ -1,9 +1,15 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
 	"github.com/supergiant/capacity/pkg/api"
@@ -16,9 +22,11 @@ var (
 )
 
 type ConfigManager interface {
-	GetConfig() api.Config
-	SetConfig(api.Config) error
-	PatchConfig(api.Config) error
+	GetConfigVersion() (api.Config, string)
+	SetConfig(ctx context.Context, conf api.Config, version string) error
+	PatchConfig(ctx context.Context, patch []byte, version string) error
+	ConfigRevisions() []api.ConfigRevision
+	RollbackConfig(ctx context.Context, revision int, version string) error
 }
 
 type configHandler struct {
@@ -47,10 +55,7 @@ func (h *configHandler) getConfig(w http.ResponseWriter, r *http.Request) {
 	//     Responses:
 	//     200: configResponse
 
-	if err := json.NewEncoder(w).Encode(h.cm.GetConfig()); err != nil {
-		log.Errorf("handle: kubescaler: get config: failed to encode")
-		w.WriteHeader(http.StatusInternalServerError)
-	}
+	h.writeConfig(w, http.StatusOK)
 }
 
 func (h *configHandler) patchConfig(w http.ResponseWriter, r *http.Request) {
@@ -58,9 +63,12 @@ func (h *configHandler) patchConfig(w http.ResponseWriter, r *http.Request) {
 	//
 	// Returns a new view of the kubescaler configuration.
 	//
-	// This will update current configuration of the application.
+	// This will update current configuration of the application. The request body is
+	// a JSON Merge Patch (RFC 7386): provided fields are replaced, null ones are removed.
+	// If the If-Match header is set, the config is updated only if its ETag matches.
 	//
 	//     Consumes:
+	//     - application/merge-patch+json
 	//     - application/json
 	//
 	//     Produces:
@@ -70,24 +78,30 @@ func (h *configHandler) patchConfig(w http.ResponseWriter, r *http.Request) {
 	//
 	//     Responses:
 	//     200: configResponse
+	//     400: description: invalid patch or the config can't be applied
+	//     409: description: config has been changed
+	//     422: validationErrorResponse
+
+	if !isMergePatch(r.Header.Get("Content-Type")) {
+		log.Errorf("handler: kubescaler: patch config: unsupported content type %q", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
 
-	patch := api.Config{}
-	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
-		log.Errorf("handler: kubescaler: patch config: decode: %v", err)
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Errorf("handler: kubescaler: patch config: read body: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if err := h.cm.PatchConfig(patch); err != nil {
+	if err = h.cm.PatchConfig(r.Context(), patch, ifMatch(r)); err != nil {
 		log.Errorf("handler: kubescaler: patch config: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeConfigError(w, err)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(h.cm.GetConfig()); err != nil {
-		log.Errorf("handle: kubescaler: patch config: failed to encode")
-		w.WriteHeader(http.StatusInternalServerError)
-	}
+	h.writeConfig(w, http.StatusOK)
 }
 
 func (h *configHandler) createConfig(w http.ResponseWriter, r *http.Request) {
@@ -95,7 +109,8 @@ func (h *configHandler) createConfig(w http.ResponseWriter, r *http.Request) {
 	//
 	// Returns a view of the kubescaler configuration.
 	//
-	// This will set configuration for the application.
+	// This will set configuration for the application. If the If-Match header is set,
+	// the config is updated only if its ETag matches.
 	//
 	//     Consumes:
 	//     - application/json
@@ -107,6 +122,9 @@ func (h *configHandler) createConfig(w http.ResponseWriter, r *http.Request) {
 	//
 	//     Responses:
 	//     201: configResponse
+	//     400: description: the config can't be applied
+	//     409: description: config has been changed
+	//     422: validationErrorResponse
 	log.Info("Create config")
 
 	cfg := api.Config{}
@@ -117,16 +135,149 @@ func (h *configHandler) createConfig(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Info("Set config")
-	if err := h.cm.SetConfig(cfg); err != nil {
+	if err := h.cm.SetConfig(r.Context(), cfg, ifMatch(r)); err != nil {
 		log.Errorf("handler: kubescaler: create config: %v", err)
+		writeConfigError(w, err)
+		return
+	}
+
+	h.writeConfig(w, http.StatusCreated)
+}
+
+func (h *configHandler) listRevisions(w http.ResponseWriter, r *http.Request) {
+	// swagger:route GET /api/v1/config/revisions config listConfigRevisions
+	//
+	// Lists stored config revisions.
+	//
+	// This will show the last applied configs starting from the current one.
+	//
+	//     Produces:
+	//     - application/json
+	//
+	//     Schemes: https, http
+	//
+	//     Responses:
+	//     200: configRevisionsResponse
+
+	revisions := h.cm.ConfigRevisions()
+	for i := range revisions {
+		revisions[i].Config = kubescaler.RedactConfig(revisions[i].Config)
+	}
+
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Errorf("handler: kubescaler: list config revisions: failed to encode")
 		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (h *configHandler) rollbackConfig(w http.ResponseWriter, r *http.Request) {
+	// swagger:route POST /api/v1/config/revisions/{revision}/rollback config rollbackConfig
+	//
+	// Returns a view of the restored kubescaler configuration.
+	//
+	// This will apply the config of the provided revision. If the If-Match header is set,
+	// the config is updated only if its ETag matches.
+	//
+	//     Produces:
+	//     - application/json
+	//
+	//     Schemes: https, http
+	//
+	//     Responses:
+	//     200: configResponse
+	//     400: description: the config can't be applied
+	//     404: description: revision not found
+	//     409: description: config has been changed
+	//     422: validationErrorResponse
+
+	revision, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		log.Errorf("handler: kubescaler: rollback config: invalid revision: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = h.cm.RollbackConfig(r.Context(), revision, ifMatch(r)); err != nil {
+		log.Errorf("handler: kubescaler: rollback config: %v", err)
+		writeConfigError(w, err)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(h.cm.GetConfig()); err != nil {
-		log.Errorf("handle: kubescaler: get config: failed to encode")
+	h.writeConfig(w, http.StatusOK)
+}
+
+// writeConfig responds with the current config and its version as an ETag.
+func (h *configHandler) writeConfig(w http.ResponseWriter, status int) {
+	conf, version := h.cm.GetConfigVersion()
+	raw, err := json.Marshal(kubescaler.RedactConfig(conf))
+	if err != nil {
+		log.Errorf("handler: kubescaler: encode config: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if version != "" {
+		w.Header().Set("ETag", strconv.Quote(version))
+	}
+	w.WriteHeader(status)
+	if _, err = w.Write(append(raw, '\n')); err != nil {
+		log.Errorf("handler: kubescaler: write config: %v", err)
 	}
+}
 
-	w.WriteHeader(http.StatusCreated)
+// ifMatch returns a config version from the If-Match header. The '*' value matches
+// any version, so an empty one is returned for it.
+func ifMatch(r *http.Request) string {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	v = strings.TrimPrefix(v, "W/")
+	if v == "*" {
+		return ""
+	}
+	if unquoted, err := strconv.Unquote(v); err == nil {
+		return unquoted
+	}
+	return v
+}
+
+// writeConfigError responds with 422 and a list of invalid fields for validation errors,
+// with 400 and an error message if the config can't be applied, with 404 for unknown
+// revisions and with 409 on version conflicts.
+func writeConfigError(w http.ResponseWriter, err error) {
+	switch cause := errors.Cause(err).(type) {
+	case *api.ValidationError:
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		if err = json.NewEncoder(w).Encode(cause); err != nil {
+			log.Errorf("handler: kubescaler: write validation errors: %v", err)
+		}
+		return
+	case *kubescaler.ApplyError:
+		http.Error(w, cause.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch errors.Cause(err) {
+	case kubescaler.ErrInvalidPatch:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	case kubescaler.ErrConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case kubescaler.ErrRevisionNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// isMergePatch returns true for JSON Merge Patch requests. Plain JSON is accepted too
+// for backward compatibility.
+func isMergePatch(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == kubescaler.MergePatchType || mediaType == "application/json"
 }
@@ -17,6 +17,7 @@ var (
 type HandlerV1 struct {
 	workerHandler *workersHandler
 	configHandler *configHandler
+	statusHandler *statusHandler
 }
 
 func New(ks *kubescaler.Kubescaler) (*HandlerV1, error) {
@@ -32,9 +33,15 @@ func New(ks *kubescaler.Kubescaler) (*HandlerV1, error) {
 		return nil, err
 	}
 
+	sh, err := newStatusHandler(ks)
+	if err != nil {
+		return nil, err
+	}
+
 	return &HandlerV1{
 		workerHandler: wh,
 		configHandler: cf,
+		statusHandler: sh,
 	}, nil
 }
 
@@ -43,6 +50,10 @@ func (h *HandlerV1) RegisterTo(ks *kubescaler.Kubescaler, r *mux.Router) {
 
 	r.Path("/config").Methods(http.MethodGet).HandlerFunc(readyMiddleware(ks, h.configHandler.getConfig))
 	r.Path("/config").Methods(http.MethodPatch).HandlerFunc(readyMiddleware(ks, h.configHandler.patchConfig))
+	r.Path("/config/revisions").Methods(http.MethodGet).HandlerFunc(h.configHandler.listRevisions)
+	r.Path("/config/revisions/{revision}/rollback").Methods(http.MethodPost).HandlerFunc(h.configHandler.rollbackConfig)
+
+	r.Path("/status").Methods(http.MethodGet).HandlerFunc(h.statusHandler.getStatus)
 
 	r.Path("/machinetypes").Methods(http.MethodGet).HandlerFunc(readyMiddleware(ks, h.workerHandler.listMachineTypes))
 
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+var (
+	ErrInvalidStatusProvider = errors.New("invalid status provider")
+)
+
+type StatusProvider interface {
+	Status() api.Status
+}
+
+type statusHandler struct {
+	sp StatusProvider
+}
+
+func newStatusHandler(sp StatusProvider) (*statusHandler, error) {
+	if sp == nil {
+		return nil, ErrInvalidStatusProvider
+	}
+	return &statusHandler{sp}, nil
+}
+
+func (h *statusHandler) getStatus(w http.ResponseWriter, r *http.Request) {
+	// swagger:route GET /api/v1/status status getStatus
+	//
+	// Returns the observed scaler state.
+	//
+	// This will show the number of workers, limits in effect, a shortfall to meet
+	// the minimum and the last error.
+	//
+	//     Produces:
+	//     - application/json
+	//
+	//     Schemes: https, http
+	//
+	//     Responses:
+	//     200: statusResponse
+
+	if err := json.NewEncoder(w).Encode(h.sp.Status()); err != nil {
+		log.Errorf("handler: kubescaler: get status: failed to encode")
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
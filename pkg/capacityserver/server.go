@@ -9,8 +9,10 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 
+	"github.com/supergiant/capacity/pkg/auth"
 	"github.com/supergiant/capacity/pkg/capacityserver/handlers"
 	"github.com/supergiant/capacity/pkg/capacityserver/handlers/v1"
+	"github.com/supergiant/capacity/pkg/kubernetes/config"
 	"github.com/supergiant/capacity/pkg/kubescaler"
 	"github.com/supergiant/capacity/pkg/log"
 )
@@ -22,14 +24,22 @@ var (
 type Config struct {
 	ListenAddr        string
 	KubescalerOptions kubescaler.Options
+	Auth              auth.Config
+	TLS               TLSConfig
 }
 
 type API struct {
-	ks  *kubescaler.Kubescaler
-	srv http.Server
+	ks     *kubescaler.Kubescaler
+	srv    http.Server
+	useTLS bool
 }
 
 func New(conf Config) (*API, error) {
+	// client certificates are requested only if the client CA is set
+	if conf.Auth.Enabled(auth.ModeX509) && (!conf.TLS.Enabled() || conf.TLS.ClientCAFile == "") {
+		return nil, errors.New("setup authentication: x509 mode requires tls and a client CA file")
+	}
+
 	log.Infof("setup kubescaler...")
 
 	ks, err := kubescaler.New(conf.KubescalerOptions)
@@ -42,12 +52,17 @@ func New(conf Config) (*API, error) {
 		return nil, errors.Wrap(err, "setup router")
 	}
 
-	h, err := handlers.RegisterRouter(ks, handlerV1)
+	authenticators, err := setupAuth(conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "setup authentication")
+	}
+
+	h, err := handlers.RegisterRouter(ks, handlerV1, auth.Middleware(authenticators))
 	if err != nil {
 		return nil, errors.Wrap(err, "setup handlers")
 	}
 
-	return &API{
+	a := &API{
 		ks: ks,
 		srv: http.Server{
 			Addr:         conf.ListenAddr,
@@ -56,7 +71,34 @@ func New(conf Config) (*API, error) {
 			WriteTimeout: 30 * time.Second,
 			IdleTimeout:  120 * time.Second,
 		},
-	}, nil
+	}
+
+	if (conf.TLS.CertFile == "") != (conf.TLS.KeyFile == "") {
+		return nil, errors.New("setup tls: both certificate and key files should be provided")
+	}
+	if conf.TLS.Enabled() {
+		if a.srv.TLSConfig, err = buildTLSConfig(conf.TLS); err != nil {
+			return nil, errors.Wrap(err, "setup tls")
+		}
+		a.useTLS = true
+	} else {
+		log.Warnf("tls is disabled, API (including cloud credentials) is served in clear text")
+	}
+
+	return a, nil
+}
+
+func setupAuth(conf Config) ([]auth.Authenticator, error) {
+	if len(conf.Auth.Modes) == 0 {
+		log.Warnf("authentication is disabled, API is available for anyone who can reach %q", conf.ListenAddr)
+		return nil, nil
+	}
+
+	kclient, err := config.GetCoreV1Client("", conf.KubescalerOptions.Kubeconfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "build kubernetes client")
+	}
+	return auth.New(conf.Auth, kclient)
 }
 
 func (a *API) Start(ctx context.Context) error {
@@ -73,9 +115,16 @@ func (a *API) Start(ctx context.Context) error {
 		{
 			name: "web server",
 			run: func() error {
-				log.Infof("listen on %q", a.srv.Addr)
-				err := a.srv.ListenAndServe()
-				if err != nil || err != http.ErrServerClosed {
+				var err error
+				if a.useTLS {
+					log.Infof("listen on %q (https)", a.srv.Addr)
+					// certificates are provided by the TLSConfig.GetCertificate callback
+					err = a.srv.ListenAndServeTLS("", "")
+				} else {
+					log.Infof("listen on %q", a.srv.Addr)
+					err = a.srv.ListenAndServe()
+				}
+				if err != nil && err != http.ErrServerClosed {
 					return err
 				}
 				return nil
@@ -85,6 +134,8 @@ func (a *API) Start(ctx context.Context) error {
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	errsCh := make(chan error, len(routines)*2)
 	wg := sync.WaitGroup{}
 	for _, r := range routines {
@@ -101,7 +152,8 @@ func (a *API) Start(ctx context.Context) error {
 				<-ctx.Done()
 
 				log.Infof("terminating %s (force exit after %s)", routine.name, shutdownTimeout.String())
-				ctx, _ := context.WithTimeout(context.Background(), shutdownTimeout)
+				ctx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+				defer cancelShutdown()
 				errch <- errors.Wrapf(routine.shutdown(ctx), "%s: shutdown", routine.name)
 			}()
 			// "run" routine: exit on failure
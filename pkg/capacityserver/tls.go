@@ -0,0 +1,177 @@
+package capacityserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/cert"
+
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+var (
+	certCheckInterval = time.Second * 10
+
+	selfSignedHost     = "capacity-service"
+	selfSignedAltNames = []string{"localhost"}
+)
+
+// TLSConfig holds parameters for serving the API over https.
+type TLSConfig struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	// SelfSigned enables generating of a self-signed certificate if cert/key files
+	// aren't provided or don't exist yet.
+	SelfSigned bool
+}
+
+// Enabled returns true if the API should be served over https.
+func (c TLSConfig) Enabled() bool {
+	return c.SelfSigned || (c.CertFile != "" && c.KeyFile != "")
+}
+
+func buildTLSConfig(conf TLSConfig) (*tls.Config, error) {
+	if conf.SelfSigned {
+		if err := ensureSelfSigned(&conf); err != nil {
+			return nil, errors.Wrap(err, "bootstrap self-signed certificate")
+		}
+	}
+
+	reloader, err := newCertReloader(conf.CertFile, conf.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConf := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if conf.ClientCAFile != "" {
+		raw, err := ioutil.ReadFile(conf.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "read client CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(raw) {
+			return nil, errors.Errorf("no certificates found in %q", conf.ClientCAFile)
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConf, nil
+}
+
+// ensureSelfSigned generates a self-signed certificate if cert/key files don't exist.
+// It's stored to the provided paths (or to a temporary directory), so it could be replaced
+// by a real one later and picked up without restart.
+func ensureSelfSigned(conf *TLSConfig) error {
+	if conf.CertFile != "" && conf.KeyFile != "" {
+		_, certErr := os.Stat(conf.CertFile)
+		_, keyErr := os.Stat(conf.KeyFile)
+		if certErr == nil && keyErr == nil {
+			return nil
+		}
+	} else {
+		dir, err := ioutil.TempDir("", "capacity-tls")
+		if err != nil {
+			return err
+		}
+		conf.CertFile, conf.KeyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	}
+
+	certPEM, keyPEM, err := cert.GenerateSelfSignedCertKey(selfSignedHost, nil, selfSignedAltNames)
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(conf.CertFile, certPEM, 0644); err != nil {
+		return errors.Wrap(err, "write certificate")
+	}
+	if err = ioutil.WriteFile(conf.KeyFile, keyPEM, 0600); err != nil {
+		return errors.Wrap(err, "write key")
+	}
+
+	log.Warnf("tls: using a self-signed certificate %q, replace it with a trusted one", conf.CertFile)
+	return nil
+}
+
+// certReloader serves a certificate and reloads it once cert/key files have been changed.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	checked     time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate is used as a tls.Config.GetCertificate callback.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	checked := r.checked
+	r.mu.RUnlock()
+
+	if time.Since(checked) > certCheckInterval {
+		if err := r.reload(); err != nil {
+			// keep serving the previous certificate
+			log.Errorf("tls: reload certificate: %v", err)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.checked = time.Now()
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if r.cert != nil && certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return nil
+	}
+
+	c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "load key pair")
+	}
+	if r.cert != nil {
+		log.Infof("tls: certificate %q has been reloaded", r.certFile)
+	}
+
+	r.cert = &c
+	r.certModTime = certInfo.ModTime()
+	r.keyModTime = keyInfo.ModTime()
+	return nil
+}
@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tcs := []struct {
+		expr        string
+		expectedErr error
+	}{
+		{expr: "* * * * *"},
+		{expr: "0 8 * * mon-fri"},
+		{expr: "*/15 0-6,22,23 1 jan,jul 7"},
+		{expr: "@daily"},
+		{expr: "5/10 * * * *"},
+		{expr: "* * * *", expectedErr: ErrInvalidExpression},
+		{expr: "60 * * * *", expectedErr: ErrInvalidExpression},
+		{expr: "* 10-2 * * *", expectedErr: ErrInvalidExpression},
+		{expr: "*/0 * * * *", expectedErr: ErrInvalidExpression},
+		{expr: "* * 0 * *", expectedErr: ErrInvalidExpression},
+		{expr: "* * * foo *", expectedErr: ErrInvalidExpression},
+	}
+
+	for i, tc := range tcs {
+		_, err := Parse(tc.expr)
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: %v", i+1, err)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	// Monday
+	monday := time.Date(2018, time.October, 1, 8, 0, 0, 0, time.UTC)
+
+	tcs := []struct {
+		expr     string
+		time     time.Time
+		expected bool
+	}{
+		{expr: "0 8 * * mon-fri", time: monday, expected: true},
+		{expr: "0 8 * * mon-fri", time: monday.Add(time.Minute)},
+		{expr: "0 8 * * mon-fri", time: monday.AddDate(0, 0, 5)},
+		{expr: "0 8 * * 0", time: monday.AddDate(0, 0, 6), expected: true},
+		{expr: "0 8 * * 7", time: monday.AddDate(0, 0, 6), expected: true},
+		{expr: "*/20 * * * *", time: monday.Add(40 * time.Minute), expected: true},
+		{expr: "*/20 * * * *", time: monday.Add(50 * time.Minute)},
+		// day of month or day of week
+		{expr: "0 8 15 * mon", time: monday, expected: true},
+		{expr: "0 8 1 * fri", time: monday, expected: true},
+		{expr: "0 8 2 * fri", time: monday},
+		{expr: "0 8 1 * *", time: monday, expected: true},
+		{expr: "@monthly", time: monday.Add(-8 * time.Hour), expected: true},
+	}
+
+	for i, tc := range tcs {
+		expr, err := Parse(tc.expr)
+		require.Nilf(t, err, "TC#%d", i+1)
+		require.Equalf(t, tc.expected, expr.Matches(tc.time), "TC#%d", i+1)
+	}
+}
@@ -11,28 +11,32 @@ import (
 	"k8s.io/client-go/tools/cache"
 )
 
-// Registry is a registry providing listers to list all pods or nodes.
+// Registry is a registry providing listers to list all pods or nodes and to get volumes.
 type Registry interface {
 	AllNodeLister() NodeLister
 	AllPodLister() PodLister
+	VolumeLister() VolumeLister
 }
 
 type RegistryImpl struct {
 	allNodeLister NodeLister
 	allPodLister  PodLister
+	volumeLister  VolumeLister
 }
 
-// NewRegistry returns a registry providing listers to list all pods or nodes.
-func NewRegistry(allNode NodeLister, allPod PodLister) Registry {
+// NewRegistry returns a registry providing listers to list all pods or nodes and to get volumes.
+func NewRegistry(allNode NodeLister, allPod PodLister, volume VolumeLister) Registry {
 	return RegistryImpl{
 		allNodeLister: allNode,
 		allPodLister:  allPod,
+		volumeLister:  volume,
 	}
 }
 
 // NewRegistryWithDefaultListers returns a registry filled with listers of the default implementations.
 func NewRegistryWithDefaultListers(restclient rest.Interface, stopChannel <-chan struct{}) Registry {
-	return NewRegistry(NewAllNodeLister(restclient, stopChannel), NewAllPodLister(restclient, stopChannel))
+	return NewRegistry(NewAllNodeLister(restclient, stopChannel), NewAllPodLister(restclient, stopChannel),
+		NewVolumeLister(restclient, stopChannel))
 }
 
 // AllNodeLister returns the AllNodeLister registered to this registry.
@@ -45,6 +49,11 @@ func (r RegistryImpl) AllPodLister() PodLister {
 	return r.allPodLister
 }
 
+// VolumeLister returns the VolumeLister registered to this registry.
+func (r RegistryImpl) VolumeLister() VolumeLister {
+	return r.volumeLister
+}
+
 // PodLister lists pods.
 type PodLister interface {
 	List() ([]*apiv1.Pod, error)
@@ -108,3 +117,43 @@ func NewAllNodeLister(restclient rest.Interface, stopchannel <-chan struct{}) No
 		nodeLister: nodeLister,
 	}
 }
+
+// VolumeLister gets persistent volume claims and volumes.
+type VolumeLister interface {
+	GetClaim(namespace, name string) (*apiv1.PersistentVolumeClaim, error)
+	GetVolume(name string) (*apiv1.PersistentVolume, error)
+}
+
+// CachedVolumeLister gets persistent volume claims and volumes from the informer caches.
+type CachedVolumeLister struct {
+	claimLister  v1lister.PersistentVolumeClaimLister
+	volumeLister v1lister.PersistentVolumeLister
+}
+
+// GetClaim returns the persistent volume claim.
+func (l *CachedVolumeLister) GetClaim(namespace, name string) (*apiv1.PersistentVolumeClaim, error) {
+	return l.claimLister.PersistentVolumeClaims(namespace).Get(name)
+}
+
+// GetVolume returns the persistent volume.
+func (l *CachedVolumeLister) GetVolume(name string) (*apiv1.PersistentVolume, error) {
+	return l.volumeLister.Get(name)
+}
+
+// NewVolumeLister builds a lister that gets persistent volume claims and volumes of all namespaces.
+func NewVolumeLister(restclient rest.Interface, stopchannel <-chan struct{}) VolumeLister {
+	claimListWatch := cache.NewListWatchFromClient(restclient, "persistentvolumeclaims", apiv1.NamespaceAll, fields.Everything())
+	claimStore := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	claimReflector := cache.NewReflector(claimListWatch, &apiv1.PersistentVolumeClaim{}, claimStore, time.Hour)
+	go claimReflector.Run(stopchannel)
+
+	volumeListWatch := cache.NewListWatchFromClient(restclient, "persistentvolumes", apiv1.NamespaceAll, fields.Everything())
+	volumeStore := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	volumeReflector := cache.NewReflector(volumeListWatch, &apiv1.PersistentVolume{}, volumeStore, time.Hour)
+	go volumeReflector.Run(stopchannel)
+
+	return &CachedVolumeLister{
+		claimLister:  v1lister.NewPersistentVolumeClaimLister(claimStore),
+		volumeLister: v1lister.NewPersistentVolumeLister(volumeStore),
+	}
+}
@@ -0,0 +1,195 @@
+package kubescaler
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/log"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+// allocatableMachines returns copies of the machine types sized by resources available for pods on
+// a new node. Allocatable resources of ready nodes of the type are used, the capacity without the
+// reserved part and pods limits of the config otherwise. Extended resources of the config are set
+// for the types, ones advertised by the nodes take precedence. Requests of DaemonSet pods running on
+// the nodes of the type are subtracted as well, as they would land on a new worker too, DaemonSets of
+// all nodes are used for types without nodes. Types without room for a pod are skipped.
+func allocatableMachines(machineTypes []*provider.MachineType, cfg api.Config, workerList *api.WorkerList,
+	nodes []*corev1.Node, pods []*corev1.Pod) []*provider.MachineType {
+	typeNodes := machineTypeNodes(workerList, nodes)
+	learned := learnedAllocatable(typeNodes)
+	clusterOverhead := daemonSetOverhead(pods, nodes)
+
+	var reservedCPU, reservedMem resource.Quantity
+	if cfg.NodeReserved != nil {
+		// quantities are checked on validation
+		reservedCPU, _ = resource.ParseQuantity(cfg.NodeReserved.CPU)
+		reservedMem, _ = resource.ParseQuantity(cfg.NodeReserved.Memory)
+	}
+
+	out := make([]*provider.MachineType, 0, len(machineTypes))
+	for _, m := range machineTypes {
+		mtype := *m
+		mtype.ExtendedResources = configResources(m.ExtendedResources, cfg.MachineTypeResources[m.Name])
+		if alloc, ok := learned[m.Name]; ok {
+			mtype.CPUResource = alloc[corev1.ResourceCPU]
+			mtype.MemoryResource = alloc[corev1.ResourceMemory]
+			if storage, ok := alloc[corev1.ResourceEphemeralStorage]; ok && !m.StorageResource.IsZero() {
+				mtype.StorageResource = storage
+			}
+			if maxPods, ok := alloc[corev1.ResourcePods]; ok {
+				mtype.MaxPods = int(maxPods.Value())
+			}
+			for name, q := range alloc {
+				if isExtendedResource(name) {
+					if mtype.ExtendedResources == nil {
+						mtype.ExtendedResources = make(map[string]resource.Quantity)
+					}
+					mtype.ExtendedResources[string(name)] = q
+				}
+			}
+		} else {
+			mtype.CPUResource = subFloor(m.CPUResource, reservedCPU)
+			mtype.MemoryResource = subFloor(m.MemoryResource, reservedMem)
+			if cfg.MaxPodsPerNode > 0 && (mtype.MaxPods == 0 || cfg.MaxPodsPerNode < mtype.MaxPods) {
+				mtype.MaxPods = cfg.MaxPodsPerNode
+			}
+		}
+
+		// DaemonSets may be limited to some types by node selectors, affinity or taints
+		overhead := clusterOverhead
+		if typeNodes[m.Name] != nil {
+			overhead = daemonSetOverhead(pods, typeNodes[m.Name])
+		}
+		mtype.CPUResource = subFloor(mtype.CPUResource, overhead[corev1.ResourceCPU])
+		mtype.MemoryResource = subFloor(mtype.MemoryResource, overhead[corev1.ResourceMemory])
+		if !mtype.StorageResource.IsZero() {
+			mtype.StorageResource = subFloor(mtype.StorageResource, overhead[corev1.ResourceEphemeralStorage])
+		}
+		if mtype.MaxPods > 0 {
+			dsPods := overhead[corev1.ResourcePods]
+			if mtype.MaxPods <= int(dsPods.Value()) {
+				log.Debugf("kubescaler: %s machine type: no room for pods besides %d DaemonSet ones", m.Name, dsPods.Value())
+				continue
+			}
+			mtype.MaxPods -= int(dsPods.Value())
+		}
+		out = append(out, &mtype)
+	}
+	return out
+}
+
+// configResources returns a copy of the machine type extended resources with config ones added,
+// quantities are checked on validation.
+func configResources(resources map[string]resource.Quantity, cfg map[string]string) map[string]resource.Quantity {
+	if len(resources) == 0 && len(cfg) == 0 {
+		return nil
+	}
+	out := make(map[string]resource.Quantity, len(resources)+len(cfg))
+	for name, q := range resources {
+		out[name] = q
+	}
+	for name, val := range cfg {
+		if q, err := resource.ParseQuantity(val); err == nil {
+			out[name] = q
+		}
+	}
+	return out
+}
+
+// machineTypeNodes groups the nodes by machine types of their workers.
+func machineTypeNodes(workerList *api.WorkerList, nodes []*corev1.Node) map[string][]*corev1.Node {
+	nodeTypes := make(map[string]string)
+	if workerList != nil {
+		for _, w := range workerList.Items {
+			if w.NodeName != "" {
+				nodeTypes[w.NodeName] = w.MachineType
+			}
+		}
+	}
+
+	out := make(map[string][]*corev1.Node)
+	for _, node := range nodes {
+		if mtype, ok := nodeTypes[node.Name]; ok {
+			out[mtype] = append(out[mtype], node)
+		}
+	}
+	return out
+}
+
+// learnedAllocatable returns the smallest allocatable resources of nodes for each machine type,
+// extended resources are taken into account as well.
+func learnedAllocatable(typeNodes map[string][]*corev1.Node) map[string]corev1.ResourceList {
+	out := make(map[string]corev1.ResourceList)
+	for mtype, nodes := range typeNodes {
+		min := corev1.ResourceList{}
+		for _, node := range nodes {
+			for name, q := range node.Status.Allocatable {
+				if !isLearnedResource(name) {
+					continue
+				}
+				if cur, ok := min[name]; !ok || q.Cmp(cur) < 0 {
+					min[name] = q.DeepCopy()
+				}
+			}
+		}
+		out[mtype] = min
+	}
+	return out
+}
+
+func isLearnedResource(name corev1.ResourceName) bool {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage, corev1.ResourcePods:
+		return true
+	}
+	return isExtendedResource(name)
+}
+
+// daemonSetOverhead returns requests of DaemonSet pods running on the nodes: the largest pod requests
+// of each DaemonSet in total and a number of DaemonSets as pod slots.
+func daemonSetOverhead(pods []*corev1.Pod, nodes []*corev1.Node) corev1.ResourceList {
+	onNodes := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		onNodes[node.Name] = true
+	}
+
+	perDaemonSet := make(map[types.UID]corev1.ResourceList)
+	for _, pod := range pods {
+		if !onNodes[pod.Spec.NodeName] || !hasDaemonSetController(pod) {
+			continue
+		}
+		uid := metav1.GetControllerOf(pod).UID
+		max, ok := perDaemonSet[uid]
+		if !ok {
+			max = corev1.ResourceList{}
+			perDaemonSet[uid] = max
+		}
+		for name, q := range podRequests(pod) {
+			if cur, ok := max[name]; !ok || q.Cmp(cur) > 0 {
+				max[name] = q.DeepCopy()
+			}
+		}
+	}
+
+	out := corev1.ResourceList{
+		corev1.ResourcePods: *resource.NewQuantity(int64(len(perDaemonSet)), resource.DecimalSI),
+	}
+	for _, requests := range perDaemonSet {
+		addScalar(out, requests)
+	}
+	return out
+}
+
+// subFloor returns a difference of the quantities, a negative one is rounded to zero.
+func subFloor(q, sub resource.Quantity) resource.Quantity {
+	out := q.DeepCopy()
+	out.Sub(sub)
+	if out.Sign() < 0 {
+		return resource.Quantity{}
+	}
+	return out
+}
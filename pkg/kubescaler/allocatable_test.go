@@ -0,0 +1,143 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+func testDaemonSetPod(uid types.UID, nodeName, cpu, mem string) *corev1.Pod {
+	pod := testPod(nodeName, cpu, mem)
+	pod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", UID: uid, Controller: &trueVar}}
+	return pod
+}
+
+func TestAllocatableMachines(t *testing.T) {
+	workerList := &api.WorkerList{
+		Items: []*api.Worker{
+			{MachineType: vmM4LargePrice02CPU2Mem4G.Name, NodeName: "node-1"},
+		},
+	}
+	nodes := []*corev1.Node{
+		testNode("node-1", "1900m", "7Gi"),
+		testNode("node-2", "4", "16Gi"),
+	}
+	pods := []*corev1.Pod{
+		testDaemonSetPod("ds-1", "node-1", "100m", "50Mi"),
+		testDaemonSetPod("ds-1", "node-2", "100m", "100Mi"),
+		testDaemonSetPod("ds-2", "node-2", "50m", "100Mi"),
+		testDaemonSetPod("ds-3", "node-3", "1", "1Gi"),
+		testPod("node-1", "1", "1Gi"),
+	}
+
+	// only ds-1 runs on nodes of the first type, DaemonSets of all nodes are used for the second one
+	tcs := []struct {
+		reserved    *api.NodeReserved
+		expectedCPU []string
+		expectedMem []string
+	}{
+		{
+			expectedCPU: []string{"1800m", "3850m"},
+			expectedMem: []string{"7118Mi", "16184Mi"},
+		},
+		{
+			reserved:    &api.NodeReserved{CPU: "200m", Memory: "1Gi"},
+			expectedCPU: []string{"1800m", "3650m"},
+			expectedMem: []string{"7118Mi", "15160Mi"},
+		},
+		{
+			reserved:    &api.NodeReserved{CPU: "8"},
+			expectedCPU: []string{"1800m", "0"},
+			expectedMem: []string{"7118Mi", "16184Mi"},
+		},
+	}
+
+	vmTypes := []*provider.MachineType{&vmM4LargePrice02CPU2Mem4G, &vmM4xLargePrice02CPU2Mem4G}
+	for i, tc := range tcs {
+		mtypes := allocatableMachines(vmTypes, api.Config{NodeReserved: tc.reserved}, workerList, nodes, pods)
+		require.Lenf(t, mtypes, len(vmTypes), "TC#%d", i+1)
+		for j, m := range mtypes {
+			require.Equalf(t, vmTypes[j].Name, m.Name, "TC#%d", i+1)
+			cpu, mem := resource.MustParse(tc.expectedCPU[j]), resource.MustParse(tc.expectedMem[j])
+			require.Zerof(t, cpu.Cmp(m.CPUResource), "TC#%d: %s: cpu %s", i+1, m.Name, m.CPUResource.String())
+			require.Zerof(t, mem.Cmp(m.MemoryResource), "TC#%d: %s: memory %s", i+1, m.Name, m.MemoryResource.String())
+		}
+	}
+
+	// the original machine types are kept untouched
+	require.Equal(t, "2", vmM4LargePrice02CPU2Mem4G.CPUResource.String())
+	require.Equal(t, "16Gi", vmM4xLargePrice02CPU2Mem4G.MemoryResource.String())
+}
+
+func TestAllocatableMachinesMaxPods(t *testing.T) {
+	small, large, unknown := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G, vmM42xLargePrice02CPU2Mem4G
+	small.MaxPods, large.MaxPods = 3, 58
+	learned := vmR5LargePrice02CPU2Mem4G
+
+	workerList := &api.WorkerList{
+		Items: []*api.Worker{
+			{MachineType: learned.Name, NodeName: "node-1"},
+		},
+	}
+	node := testNode("node-1", "2", "16Gi")
+	node.Status.Allocatable[corev1.ResourcePods] = resource.MustParse("29")
+	pods := []*corev1.Pod{
+		testDaemonSetPod("ds-1", "node-1", "100m", "50Mi"),
+		testDaemonSetPod("ds-2", "node-1", "100m", "50Mi"),
+		testDaemonSetPod("ds-3", "node-1", "100m", "50Mi"),
+	}
+
+	mtypes := allocatableMachines([]*provider.MachineType{&small, &large, &unknown, &learned}, api.Config{MaxPodsPerNode: 30},
+		workerList, []*corev1.Node{node}, pods)
+	maxPods := make(map[string]int)
+	for _, m := range mtypes {
+		maxPods[m.Name] = m.MaxPods
+	}
+	require.Equal(t, map[string]int{
+		large.Name:   27,
+		unknown.Name: 27,
+		learned.Name: 26,
+	}, maxPods)
+}
+
+func TestAllocatableMachinesExtended(t *testing.T) {
+	small, large, gpu := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G, vmP2xLarge
+	workerList := &api.WorkerList{
+		Items: []*api.Worker{
+			{MachineType: large.Name, NodeName: "node-1"},
+			{MachineType: gpu.Name, NodeName: "node-2"},
+		},
+	}
+	node1 := testNode("node-1", "4", "16Gi")
+	node1.Status.Allocatable["example.com/fpga"] = resource.MustParse("2")
+	node2 := testNode("node-2", "4", "61Gi")
+	node2.Status.Allocatable[provider.ResourceGPU] = resource.MustParse("0")
+	cfg := api.Config{MachineTypeResources: map[string]map[string]string{
+		small.Name: {"example.com/fpga": "1"},
+	}}
+
+	mtypes := allocatableMachines([]*provider.MachineType{&small, &large, &gpu}, cfg, workerList, []*corev1.Node{node1, node2}, nil)
+	extended := make(map[string]map[string]resource.Quantity)
+	for _, m := range mtypes {
+		extended[m.Name] = m.Extended()
+	}
+	require.Equal(t, map[string]map[string]resource.Quantity{
+		small.Name: {"example.com/fpga": resource.MustParse("1")},
+		large.Name: {"example.com/fpga": resource.MustParse("2")},
+		// the device plugin doesn't advertise GPUs yet
+		gpu.Name: {provider.ResourceGPU: resource.MustParse("0")},
+	}, extended)
+	require.Nil(t, small.ExtendedResources)
+
+	podFPGA := testExtendedPod("1", "1Gi", corev1.ResourceList{"example.com/fpga": resource2})
+	mtype, err := machineToScale([]*corev1.Pod{podFPGA}, mtypes, api.BigBox, nil)
+	require.Nil(t, err)
+	require.Equal(t, large.Name, mtype.Name)
+}
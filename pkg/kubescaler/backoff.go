@@ -0,0 +1,106 @@
+package kubescaler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+const (
+	// initialCreateBackoff is a time a machine type isn't used after the first failure to create
+	// a worker, it's doubled on each subsequent failure up to maxCreateBackoff.
+	initialCreateBackoff = time.Minute
+	maxCreateBackoff     = 30 * time.Minute
+
+	// maxCreateAttempts limits a number of machine types tried on a single scale up or
+	// a scale up to workersCountMin.
+	maxCreateAttempts = 3
+)
+
+// machineBackoff tracks failures to create workers by machine types, only provider.MachineTypeError
+// ones are counted, e.g. if the provider is out of capacity or the type quota is exceeded.
+// A zero value is ready to use.
+type machineBackoff struct {
+	mu    sync.Mutex
+	items map[string]*api.MachineTypeBackoff
+}
+
+// failed records a failure and returns a backoff duration for the machine type.
+func (b *machineBackoff) failed(mtype string, err error, now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.items == nil {
+		b.items = make(map[string]*api.MachineTypeBackoff)
+	}
+	item, ok := b.items[mtype]
+	if !ok {
+		item = &api.MachineTypeBackoff{MachineType: mtype}
+		b.items[mtype] = item
+	}
+
+	d := initialCreateBackoff
+	for i := 0; i < item.Failures && d < maxCreateBackoff; i++ {
+		d *= 2
+	}
+	if d > maxCreateBackoff {
+		d = maxCreateBackoff
+	}
+
+	item.Failures++
+	item.Until = now.Add(d)
+	item.LastError = err.Error()
+	return d
+}
+
+// succeeded resets the machine type backoff.
+func (b *machineBackoff) succeeded(mtype string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.items, mtype)
+}
+
+// available returns machine types that aren't backed off.
+func (b *machineBackoff) available(machineTypes []*provider.MachineType, now time.Time) []*provider.MachineType {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]*provider.MachineType, 0, len(machineTypes))
+	for _, m := range machineTypes {
+		if item, ok := b.items[m.Name]; ok && now.Before(item.Until) {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// list returns machine types that are backed off at the time, sorted by name.
+func (b *machineBackoff) list(now time.Time) []api.MachineTypeBackoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make([]api.MachineTypeBackoff, 0)
+	for _, item := range b.items {
+		if now.Before(item.Until) {
+			out = append(out, *item)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].MachineType < out[j].MachineType
+	})
+	return out
+}
+
+func withoutMachine(machineTypes []*provider.MachineType, name string) []*provider.MachineType {
+	out := make([]*provider.MachineType, 0, len(machineTypes))
+	for _, m := range machineTypes {
+		if m.Name != name {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package kubescaler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/kubescaler/workers/fake"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+// failingManager fails to create workers of the machine types with a MachineTypeError,
+// all workers fail with err if it's set.
+type failingManager struct {
+	*fake.Manager
+	failing map[string]bool
+	err     error
+	created []string
+}
+
+func (m *failingManager) CreateWorker(ctx context.Context, mtype string) (*api.Worker, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.failing[mtype] {
+		return nil, errors.Wrap(&provider.MachineTypeError{Err: errFake}, "create machine")
+	}
+	m.created = append(m.created, mtype)
+	return m.Manager.CreateWorker(ctx, mtype)
+}
+
+func (m *failingManager) CreateWorkerInZone(ctx context.Context, mtype, zone string) (*api.Worker, error) {
+	return m.CreateWorker(ctx, mtype)
+}
+
+func TestMachineBackoff(t *testing.T) {
+	var b machineBackoff
+	small, large := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G
+	mtypes := []*provider.MachineType{&small, &large}
+
+	require.Equal(t, mtypes, b.available(mtypes, currentTime))
+	require.Empty(t, b.list(currentTime))
+
+	tcs := []struct {
+		expected time.Duration
+	}{
+		{time.Minute},
+		{2 * time.Minute},
+		{4 * time.Minute},
+		{8 * time.Minute},
+		{16 * time.Minute},
+		{30 * time.Minute},
+		{30 * time.Minute},
+	}
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expected, b.failed(small.Name, errFake, currentTime), "TC#%d", i+1)
+	}
+
+	require.Equal(t, []*provider.MachineType{&large}, b.available(mtypes, currentTime))
+	require.Equal(t, mtypes, b.available(mtypes, currentTime.Add(maxCreateBackoff)))
+	require.Equal(t, []api.MachineTypeBackoff{{
+		MachineType: small.Name,
+		Failures:    len(tcs),
+		Until:       currentTime.Add(maxCreateBackoff),
+		LastError:   errFake.Error(),
+	}}, b.list(currentTime))
+	require.Empty(t, b.list(currentTime.Add(maxCreateBackoff)))
+
+	b.succeeded(small.Name)
+	require.Equal(t, mtypes, b.available(mtypes, currentTime))
+	require.Equal(t, time.Minute, b.failed(small.Name, errFake, currentTime))
+}
+
+func TestScaleUpFallback(t *testing.T) {
+	small, large := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G
+	mtypes := []*provider.MachineType{&small, &large}
+	pods := []*corev1.Pod{&podWithRequests}
+
+	m := &failingManager{Manager: fake.NewManager(nil), failing: map[string]bool{small.Name: true}}
+	ks := &Kubescaler{workerManager: m}
+
+	// the cheapest type fails, the next one is used
+	scaled, err := ks.scaleUp(pods, nil, podFilter{}, mtypes, "", nil, currentTime)
+	require.Nil(t, err)
+	require.True(t, scaled)
+	require.Equal(t, []string{large.Name}, m.created)
+	require.Len(t, ks.Status().Backoff, 1)
+
+	// the backed off type isn't tried again
+	m.failing = nil
+	_, err = ks.scaleUp(pods, nil, podFilter{}, mtypes, "", nil, currentTime)
+	require.Nil(t, err)
+	require.Equal(t, []string{large.Name, large.Name}, m.created)
+
+	// all types fail
+	m.failing = map[string]bool{small.Name: true, large.Name: true}
+	scaled, err = ks.scaleUp(pods, nil, podFilter{}, mtypes, "", nil, currentTime.Add(maxCreateBackoff))
+	require.False(t, scaled)
+	require.True(t, provider.IsMachineTypeError(err))
+
+	// nothing is left to try
+	scaled, err = ks.scaleUp(pods, nil, podFilter{}, mtypes, "", nil, currentTime.Add(maxCreateBackoff))
+	require.Nil(t, err)
+	require.False(t, scaled)
+}
+
+func TestScaleUpNotBackedOff(t *testing.T) {
+	small := vmM4LargePrice02CPU2Mem4G
+	m := &failingManager{Manager: fake.NewManager(nil), err: errFake}
+	ks := &Kubescaler{workerManager: m}
+
+	// errors that aren't specific to the machine type don't back it off
+	scaled, err := ks.scaleUp([]*corev1.Pod{&podWithRequests}, nil, podFilter{}, []*provider.MachineType{&small}, "", nil, currentTime)
+	require.False(t, scaled)
+	require.Equal(t, errFake, errors.Cause(err))
+	require.Equal(t, errFake, errors.Cause(ks.scaleToMin(1, small.Name, []*provider.MachineType{&small}, currentTime)))
+	require.Empty(t, ks.Status().Backoff)
+}
+
+func TestScaleToMinFallback(t *testing.T) {
+	mtypes := []*provider.MachineType{
+		&vmM4LargePrice02CPU2Mem4G,
+		&vmM4xLargePrice02CPU2Mem4G,
+		&vmR5LargePrice02CPU2Mem4G,
+		&vmR5xLargePrice02CPU2Mem4G,
+	}
+	failing := make(map[string]bool, len(mtypes))
+	for _, m := range mtypes {
+		failing[m.Name] = true
+	}
+	m := &failingManager{Manager: fake.NewManager(nil), failing: failing}
+	ks := &Kubescaler{workerManager: m}
+
+	// the number of tried types is limited
+	err := ks.scaleToMin(1, "", mtypes, currentTime)
+	require.True(t, provider.IsMachineTypeError(err))
+	require.Len(t, ks.Status().Backoff, maxCreateAttempts)
+
+	// the left one is used
+	m.failing = nil
+	require.Nil(t, ks.scaleToMin(2, "", mtypes, currentTime))
+	require.Len(t, m.created, 2)
+	require.Equal(t, m.created[0], m.created[1])
+	require.Len(t, ks.Status().Backoff, maxCreateAttempts)
+}
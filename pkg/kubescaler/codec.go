@@ -0,0 +1,139 @@
+package kubescaler
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Supported config formats:
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+var ErrUnknownFormat = errors.New("unknown config format")
+
+// Codec encodes and decodes configs. All formats use json field names.
+type Codec interface {
+	Encode(v interface{}) ([]byte, error)
+	Decode(data []byte, v interface{}) error
+}
+
+// FormatOf detects a config format by the file name extension. JSON is used for unknown
+// extensions (e.g. for the 'kubescaler.conf' file).
+func FormatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// CodecFor returns a codec for the provided format.
+func CodecFor(format string) (Codec, error) {
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return jsonCodec{}, nil
+	case FormatYAML, "yml":
+		return yamlCodec{}, nil
+	case FormatTOML:
+		return tomlCodec{}, nil
+	}
+	return nil, errors.Wrap(ErrUnknownFormat, format)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Decode(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Encode(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlCodec) Decode(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
+
+// tomlCodec converts values through json, so json field names and omitempty options
+// are respected.
+type tomlCodec struct{}
+
+func (tomlCodec) Encode(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	var tree interface{}
+	if err = d.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err = toml.NewEncoder(buf).Encode(tomlValue(tree)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (tomlCodec) Decode(data []byte, v interface{}) error {
+	tree := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &tree); err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// tomlValue drops nulls that can't be represented in TOML and converts json numbers
+// to integers where it's possible.
+func tomlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if val != nil {
+				out[k] = tomlValue(val)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			if val != nil {
+				out = append(out, tomlValue(val))
+			}
+		}
+		return out
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
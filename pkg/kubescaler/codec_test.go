@@ -0,0 +1,65 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider/aws"
+)
+
+func TestFormatOf(t *testing.T) {
+	tcs := []struct {
+		name           string
+		expectedFormat string
+	}{
+		{"kubescaler.conf", FormatJSON},
+		{"/etc/kubescaler.json", FormatJSON},
+		{"kubescaler.yaml", FormatYAML},
+		{"/etc/capacity/kubescaler.YML", FormatYAML},
+		{"kubescaler.toml", FormatTOML},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expectedFormat, FormatOf(tc.name), "TC#%d", i+1)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	conf := validTestConfig()
+	conf.Paused = BoolPtr(true)
+	conf.IgnoredNodeLabels = map[string]string{"role": "db"}
+
+	for _, format := range []string{FormatJSON, FormatYAML, FormatTOML} {
+		codec, err := CodecFor(format)
+		require.Nil(t, err)
+
+		raw, err := codec.Encode(conf)
+		require.Nilf(t, err, format)
+
+		decoded := api.Config{}
+		require.Nilf(t, codec.Decode(raw, &decoded), format)
+		require.Equalf(t, conf, decoded, format)
+	}
+
+	_, err := CodecFor("xml")
+	require.NotNil(t, err)
+}
+
+func TestDecodeYAML(t *testing.T) {
+	raw := `
+clusterName: test
+providerName: aws
+provider:
+  awsRegion: us-west-1
+workersCountMax: 3
+machineTypes:
+- m4.large
+`
+	conf := api.Config{}
+	require.Nil(t, yamlCodec{}.Decode([]byte(raw), &conf))
+	require.Equal(t, "us-west-1", conf.Provider[aws.Region])
+	require.Equal(t, 3, conf.WorkersCountMax)
+	require.Equal(t, []string{"m4.large"}, conf.MachineTypes)
+}
@@ -1,129 +1,308 @@
 package kubescaler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"os"
+	"io"
 	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
 
 	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/auth"
 	"github.com/supergiant/capacity/pkg/log"
 	"github.com/supergiant/capacity/pkg/persistentfile"
 	"github.com/supergiant/capacity/pkg/provider"
 	"github.com/supergiant/capacity/pkg/provider/aws"
+	"github.com/supergiant/capacity/pkg/provider/factory"
 )
 
 const (
 	EnvPrefix = "CAPACITY"
 )
 
-func Merge(c, patch api.Config) api.Config {
-	if patch.Paused != nil {
-		c.Paused = patch.Paused
-	}
-	// TODO: use pointers for it?
-	if patch.WorkersCountMin != 0 {
-		c.WorkersCountMin = patch.WorkersCountMin
-	}
-	if patch.WorkersCountMax != 0 {
-		c.WorkersCountMax = patch.WorkersCountMax
-	}
-	if len(patch.MachineTypes) != 0 {
-		c.MachineTypes = patch.MachineTypes
-	}
-	if len(patch.IgnoredNodeLabels) != 0 {
-		c.IgnoredNodeLabels = patch.IgnoredNodeLabels
-	}
-	return c
-}
+// ErrConflict is returned if the config has been changed since the provided version.
+var ErrConflict = errors.New("config has been changed")
 
 type ConfigManager struct {
-	file persistentfile.Interface
+	file    persistentfile.Interface
+	codec   Codec
+	history *configHistory
 
 	mu   sync.RWMutex
 	conf api.Config
+	// raw is the last known file contents, it's used to skip own writes on watch.
+	raw []byte
+	// version is a version of the file the current config was read from or written to.
+	version string
+	// written keeps versions of own writes that haven't been seen on watch yet.
+	written map[string]bool
 }
 
-func NewConfigManager(file persistentfile.Interface) (*ConfigManager, error) {
-	raw, err := file.Read()
+// NewConfigManager reads the config from the file, its format is detected by the file
+// name. Up to historyLimit config revisions are kept next to it, history is disabled
+// for a not positive limit.
+func NewConfigManager(file persistentfile.Interface, historyLimit int) (*ConfigManager, error) {
+	codec, err := CodecFor(FormatOf(file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, version, err := file.Read()
 	conf := api.Config{}
 
 	// If error has happen or content is simply empty - dont try to unmarshall it
 	if err != nil || len(raw) == 0 {
 		log.Warnf("read %s config: %s", file.Info(), err)
 	} else {
-		// TODO: use codec to support more formats
-		if err = json.Unmarshal(raw, &conf); err != nil {
+		if err = codec.Decode(raw, &conf); err != nil {
 			return nil, errors.Wrap(err, "decode config")
 		}
-		log.Infof("found config: %+v", conf)
+		log.Infof("found config: %+v", RedactConfig(conf))
+	}
+
+	var history *configHistory
+	if historyLimit > 0 {
+		historyFile, err := persistentfile.Sibling(file, historyFileName)
+		if err != nil {
+			return nil, errors.Wrap(err, "setup config history")
+		}
+		history = newConfigHistory(historyFile, historyLimit)
 	}
 
 	return &ConfigManager{
-		file: file,
-		mu:   sync.RWMutex{},
-		conf: applyEnv(conf),
+		file:    file,
+		codec:   codec,
+		history: history,
+		mu:      sync.RWMutex{},
+		conf:    conf,
+		raw:     raw,
+		version: version,
+		written: make(map[string]bool),
 	}, nil
 }
 
 // methods for manipulating config
-func (m *ConfigManager) SetConfig(conf api.Config) error {
-	if err := m.write(conf); err != nil {
+
+// SetConfig replaces the current config. A not empty version should match the current one.
+func (m *ConfigManager) SetConfig(ctx context.Context, conf api.Config, version string) error {
+	if err := m.checkVersion(version); err != nil {
 		return err
 	}
+	conf, err := m.prepare(conf)
+	if err != nil {
+		return err
+	}
+	return m.store(conf, authorFrom(ctx))
+}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	m.conf = conf
-	return nil
+// PatchConfig applies a JSON Merge Patch (RFC 7386) to the current config. Masked secrets
+// are kept unchanged. A not empty version should match the current one.
+func (m *ConfigManager) PatchConfig(ctx context.Context, patch []byte, version string) error {
+	if err := m.checkVersion(version); err != nil {
+		return err
+	}
+	conf, err := m.patched(patch)
+	if err != nil {
+		return err
+	}
+	if conf, err = m.prepare(conf); err != nil {
+		return err
+	}
+	return m.store(conf, authorFrom(ctx))
 }
 
-func (m *ConfigManager) PatchConfig(in api.Config) error {
-	newConf := Merge(m.GetConfig(), in)
-	if err := newConf.Validate(); err != nil {
+// RollbackConfig restores the config of the provided revision. A not empty version should
+// match the current one.
+func (m *ConfigManager) RollbackConfig(ctx context.Context, revision int, version string) error {
+	if err := m.checkVersion(version); err != nil {
 		return err
 	}
-	return m.SetConfig(newConf)
+	conf, err := m.revisionConfig(revision)
+	if err != nil {
+		return err
+	}
+	return m.store(conf, authorFrom(ctx))
 }
 
-func (m *ConfigManager) GetConfig() api.Config {
+// Revisions returns stored config revisions starting from the latest one.
+func (m *ConfigManager) Revisions() []api.ConfigRevision {
+	if m.history == nil {
+		return []api.ConfigRevision{}
+	}
+	return m.history.list()
+}
+
+// revisionConfig returns a validated config of the provided revision.
+func (m *ConfigManager) revisionConfig(revision int) (api.Config, error) {
+	if m.history == nil {
+		return api.Config{}, errors.Wrapf(ErrRevisionNotFound, "revision %d", revision)
+	}
+	rev, err := m.history.get(revision)
+	if err != nil {
+		return api.Config{}, err
+	}
+	if err = ValidateConfig(rev.Config); err != nil {
+		return api.Config{}, err
+	}
+	return rev.Config, nil
+}
+
+// checkVersion returns ErrConflict if the version isn't empty and doesn't match
+// the current one.
+func (m *ConfigManager) checkVersion(version string) error {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.conf
+	if version != "" && version != m.version {
+		return errors.Wrapf(ErrConflict, "version %q, current %q", version, m.version)
+	}
+	return nil
+}
+
+// prepare restores masked secrets from the current config and validates the result.
+func (m *ConfigManager) prepare(conf api.Config) (api.Config, error) {
+	// keep stored secrets if masked values were sent back
+	conf = conf.WithRedactedFrom(m.GetConfig(), factory.SensitiveKeys(conf.ProviderName))
+	if err := ValidateConfig(conf); err != nil {
+		return api.Config{}, err
+	}
+	return conf, nil
+}
+
+// patched returns the current config with the patch applied, nothing is persisted.
+func (m *ConfigManager) patched(patch []byte) (api.Config, error) {
+	raw, err := json.Marshal(m.GetConfig())
+	if err != nil {
+		return api.Config{}, errors.Wrap(err, "encode config")
+	}
+
+	if raw, err = mergePatch(raw, patch); err != nil {
+		return api.Config{}, err
+	}
+
+	newConf := api.Config{}
+	if err = json.Unmarshal(raw, &newConf); err != nil {
+		return api.Config{}, errors.Wrap(ErrInvalidPatch, err.Error())
+	}
+	return newConf, nil
 }
 
-// utility functions
-func (m *ConfigManager) write(conf api.Config) error {
-	raw, err := json.Marshal(conf)
+// store persists the config and makes it the current one. The file isn't overwritten
+// if it has been changed since the current config was read.
+func (m *ConfigManager) store(conf api.Config, author string) error {
+	raw, err := m.codec.Encode(conf)
 	if err != nil {
 		return errors.Wrap(err, "encode config")
 	}
-	return errors.Wrap(m.file.Write(raw), "write config")
+
+	_, version := m.GetConfigVersion()
+	newVersion, err := m.file.Write(raw, version)
+	if err != nil {
+		if persistentfile.IsConflict(err) {
+			return errors.Wrap(ErrConflict, err.Error())
+		}
+		return errors.Wrap(err, "write config")
+	}
+
+	m.setCurrent(conf, raw, newVersion, author)
+
+	m.mu.Lock()
+	m.written[newVersion] = true
+	m.mu.Unlock()
+	return nil
 }
 
-// TODO: just a hack, use viper in the future
-func applyEnv(conf api.Config) api.Config {
-	envMap := map[string]string{
-		aws.KeyID:     EnvPrefix + "_PROVIDER_AWS_KEYID",
-		aws.SecretKey: EnvPrefix + "_PROVIDER_AWS_SECRETKEY",
+// setCurrent replaces the current config and adds it to the history.
+func (m *ConfigManager) setCurrent(conf api.Config, raw []byte, version, author string) {
+	m.mu.Lock()
+	prev := m.conf
+	m.conf = conf
+	m.raw = raw
+	m.version = version
+	m.mu.Unlock()
+
+	if m.history == nil {
+		return
 	}
+	// the config has been applied already, so don't fail on history errors
+	if err := m.history.add(conf, author, prev); err != nil {
+		log.Errorf("kubescaler: %v", err)
+	}
+}
 
-	for key, env := range envMap {
-		val := os.Getenv(env)
-		if val != "" {
-			conf.Provider[key] = val
+// watch calls the reload function each time the config file has been changed.
+func (m *ConfigManager) watch(stopCh <-chan struct{}, reload func(raw []byte, version string) error) error {
+	err := m.file.Watch(stopCh, func(raw []byte, version string) {
+		if err := reload(raw, version); err != nil {
+			log.Errorf("kubescaler: reload config from %s: %v", m.file.Info(), err)
 		}
+	})
+	return errors.Wrapf(err, "watch %s", m.file.Info())
+}
+
+// decode returns a validated config from the file contents. It returns false for own
+// writes and if the contents are the same as the last known ones.
+func (m *ConfigManager) decode(raw []byte, version string) (api.Config, bool, error) {
+	m.mu.Lock()
+	if m.written[version] {
+		// events could be delayed, don't go back to the previous own write
+		delete(m.written, version)
+		m.mu.Unlock()
+		return api.Config{}, false, nil
+	}
+	if bytes.Equal(raw, m.raw) {
+		// another ConfigMap key has been changed
+		m.version = version
+		m.mu.Unlock()
+		return api.Config{}, false, nil
+	}
+	m.mu.Unlock()
+
+	conf := api.Config{}
+	if err := m.codec.Decode(raw, &conf); err != nil {
+		return api.Config{}, false, errors.Wrap(err, "decode config")
 	}
-	return conf
+	if err := ValidateConfig(conf); err != nil {
+		return api.Config{}, false, err
+	}
+	return conf, true, nil
+}
+
+func (m *ConfigManager) GetConfig() api.Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.conf
+}
+
+// GetConfigVersion returns the current config and its version.
+func (m *ConfigManager) GetConfigVersion() (api.Config, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.conf, m.version
 }
 
-// TODO: show this on cli help subcommand
-func writeExampleConfig(file persistentfile.Interface) error {
-	conf := &api.Config{
+// authorFrom returns a name of the authenticated user, if any.
+func authorFrom(ctx context.Context) string {
+	if u, ok := auth.UserFrom(ctx); ok {
+		return u.Name
+	}
+	return ""
+}
+
+// RedactConfig returns a copy of the config with masked sensitive values,
+// it's safe to be exposed in API responses and logs.
+func RedactConfig(conf api.Config) api.Config {
+	return conf.Redacted(factory.SensitiveKeys(conf.ProviderName))
+}
+
+// ExampleConfig returns a config with placeholders for required parameters.
+func ExampleConfig() api.Config {
+	return api.Config{
 		ClusterName:  "REPLACE_IT",
 		ProviderName: "aws",
 		Provider: map[string]string{
@@ -152,13 +331,29 @@ func writeExampleConfig(file persistentfile.Interface) error {
 			SSHPubKey:         "REPLACE_IT",
 		},
 	}
+}
 
-	raw, err := json.Marshal(conf)
+// WriteExampleConfig writes the example config in the provided format.
+func WriteExampleConfig(w io.Writer, format string) error {
+	codec, err := CodecFor(format)
 	if err != nil {
 		return err
 	}
 
-	return file.Write(raw)
+	raw, err := codec.Encode(ExampleConfig())
+	if err != nil {
+		return errors.Wrap(err, "encode config")
+	}
+	if _, ok := codec.(jsonCodec); ok {
+		buf := &bytes.Buffer{}
+		if err = json.Indent(buf, raw, "", "  "); err != nil {
+			return err
+		}
+		raw = append(buf.Bytes(), '\n')
+	}
+
+	_, err = w.Write(raw)
+	return err
 }
 
 func BoolPtr(in bool) *bool {
@@ -0,0 +1,388 @@
+package kubescaler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/auth"
+	"github.com/supergiant/capacity/pkg/persistentfile/file"
+	"github.com/supergiant/capacity/pkg/provider/aws"
+)
+
+func newTestConfigManager(t *testing.T, conf api.Config) *ConfigManager {
+	f, err := file.New("/tmp/"+uuid.New(), os.FileMode(0664))
+	require.Nil(t, err)
+
+	m, err := NewConfigManager(f, 3)
+	require.Nil(t, err)
+	m.conf = conf
+	return m
+}
+
+func validTestConfig() api.Config {
+	return api.Config{
+		ClusterName:  "test",
+		ProviderName: aws.Name,
+		Provider: map[string]string{
+			aws.KeyID:     "id",
+			aws.SecretKey: "secret",
+			aws.Region:    "us-west-1",
+			aws.ImageID:   "ami-cc0900ac",
+			aws.VolSize:   "100",
+		},
+		WorkersCountMin: 1,
+		WorkersCountMax: 3,
+		MachineTypes:    []string{"m4.large"},
+		Strategy:        api.BigBox,
+		Userdata:        "#cloud-config",
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	conf := api.Config{
+		ProviderName: aws.Name,
+		Provider: map[string]string{
+			aws.KeyID:     "id",
+			aws.SecretKey: "secret",
+			aws.Region:    "us-west-1",
+		},
+		SupergiantV1Config: &api.SupergiantV1UserdataVars{
+			KubeAPIUser:     "user",
+			KubeAPIPassword: "password",
+		},
+	}
+
+	redacted := RedactConfig(conf)
+	require.Equal(t, api.RedactedValue, redacted.Provider[aws.KeyID])
+	require.Equal(t, api.RedactedValue, redacted.Provider[aws.SecretKey])
+	require.Equal(t, "us-west-1", redacted.Provider[aws.Region])
+	require.Equal(t, api.RedactedValue, redacted.SupergiantV1Config.KubeAPIPassword)
+	require.Equal(t, "user", redacted.SupergiantV1Config.KubeAPIUser)
+
+	// original config should stay unchanged
+	require.Equal(t, "secret", conf.Provider[aws.SecretKey])
+	require.Equal(t, "password", conf.SupergiantV1Config.KubeAPIPassword)
+}
+
+func TestSetConfigKeepsRedacted(t *testing.T) {
+	stored := validTestConfig()
+	stored.SupergiantV1Config = &api.SupergiantV1UserdataVars{
+		KubeAPIPassword: "password",
+	}
+	m := newTestConfigManager(t, stored)
+
+	update := RedactConfig(stored)
+	update.Provider[aws.Region] = "us-east-1"
+	update.Provider[aws.KeyID] = "newID"
+	require.Nil(t, m.SetConfig(context.Background(), update, ""))
+
+	conf := m.GetConfig()
+	require.Equal(t, "newID", conf.Provider[aws.KeyID])
+	require.Equal(t, "secret", conf.Provider[aws.SecretKey])
+	require.Equal(t, "us-east-1", conf.Provider[aws.Region])
+	require.Equal(t, "password", conf.SupergiantV1Config.KubeAPIPassword)
+}
+
+func TestPatchConfig(t *testing.T) {
+	stored := validTestConfig()
+	stored.IgnoredNodeLabels = map[string]string{"role": "db"}
+
+	tcs := []struct {
+		patch       string
+		expectedErr error
+		check       func(conf api.Config)
+	}{
+		{
+			patch: `{"workersCountMin": 0, "strategy": "smallCPUBox", "workersLifespanMinutes": 30}`,
+			check: func(conf api.Config) {
+				require.Equal(t, 0, conf.WorkersCountMin)
+				require.Equal(t, 3, conf.WorkersCountMax)
+				require.Equal(t, api.SmallCPUBox, conf.Strategy)
+				require.Equal(t, 30, conf.WorkersLifespanMinutes)
+			},
+		},
+		{
+			patch: `{"ignoredNodeLabels": null, "machineTypes": ["m4.xlarge", "m4.2xlarge"], "workersCountMax": 0}`,
+			check: func(conf api.Config) {
+				require.Nil(t, conf.IgnoredNodeLabels)
+				require.Equal(t, []string{"m4.xlarge", "m4.2xlarge"}, conf.MachineTypes)
+			},
+		},
+		{
+			patch: `{"provider": {"awsRegion": "us-east-1", "awsSecretKey": "******", "awsTags": "a=b"}}`,
+			check: func(conf api.Config) {
+				require.Equal(t, "secret", conf.Provider[aws.SecretKey])
+				require.Equal(t, "us-east-1", conf.Provider[aws.Region])
+				require.Equal(t, "a=b", conf.Provider[aws.Tags])
+			},
+		},
+		{
+			patch:       `{"workersCountMin": -1}`,
+			expectedErr: errors.New("invalid config: workersCountMin: Invalid value: -1: can't be negative"),
+		},
+		{
+			patch:       `{"workersCountMin": "one"}`,
+			expectedErr: ErrInvalidPatch,
+		},
+		{
+			patch:       `{`,
+			expectedErr: ErrInvalidPatch,
+		},
+	}
+
+	for i, tc := range tcs {
+		m := newTestConfigManager(t, stored)
+
+		err := m.PatchConfig(context.Background(), []byte(tc.patch), "")
+		if tc.expectedErr != nil {
+			require.NotNilf(t, err, "TC#%d", i+1)
+			if errors.Cause(err) == tc.expectedErr {
+				continue
+			}
+			require.Equalf(t, tc.expectedErr.Error(), err.Error(), "TC#%d", i+1)
+			continue
+		}
+		require.Nilf(t, err, "TC#%d", i+1)
+		tc.check(m.GetConfig())
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tcs := []struct {
+		update         func(conf *api.Config)
+		expectedFields []string
+	}{
+		{
+			update: func(conf *api.Config) {},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.ProviderName = "gce"
+			},
+			expectedFields: []string{"providerName"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.WorkersCountMin = 4
+				conf.ScanInterval = "20"
+				conf.Strategy = "hugeBox"
+				conf.DefaultMachineType = "m4.xlarge"
+				conf.Userdata = ""
+			},
+			expectedFields: []string{"workersCountMin", "scanInterval", "strategy", "defaultMachineType", "userdata"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.MachineTypes = []string{"m4.large", "m100.huge", "m4.large"}
+				conf.Provider = map[string]string{
+					aws.Region:  "us-west-1",
+					aws.VolSize: "100GB",
+				}
+			},
+			expectedFields: []string{
+				"machineTypes[2]",
+				"provider[awsKeyID]",
+				"provider[awsSecretKey]",
+				"provider[awsImageID]",
+				"provider[awsVolSize]",
+				"machineTypes[1]",
+			},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.Provider[aws.Region] = "mars-central-1"
+			},
+			expectedFields: []string{"provider[awsRegion]"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.Headroom = &api.Headroom{CPU: "-1", Memory: "1 Gi", Pods: -1}
+			},
+			expectedFields: []string{"headroom.cpu", "headroom.memory", "headroom.pods"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.Headroom = &api.Headroom{CPU: "0"}
+			},
+			expectedFields: []string{"headroom"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.NodeReserved = &api.NodeReserved{CPU: "-100m", Memory: "1 Gi"}
+			},
+			expectedFields: []string{"nodeReserved.cpu", "nodeReserved.memory"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.MachineTypeResources = map[string]map[string]string{
+					"m4.large":    {"example.com/fpga": "1", "memory": "1Gi", "example.com/gpu": "-1"},
+					"x1.32xlarge": {"example.com/fpga": "1"},
+				}
+			},
+			expectedFields: []string{"machineTypeResources[m4.large][example.com/gpu]", "machineTypeResources[m4.large][memory]",
+				"machineTypeResources[x1.32xlarge]"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.MaxPodsPerNode = -1
+				conf.Provider[aws.VPCCNI] = "yes"
+				conf.Provider[aws.ZoneSubnets] = "us-east-1a=subnet-1,us-east-1b"
+			},
+			expectedFields: []string{"maxPodsPerNode", "provider[awsVPCCNI]", "provider[awsZoneSubnets]"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.IgnoredPodNamespaces = []string{"ci", " "}
+				conf.IgnoredPodSelectors = []string{"app=ci", "app in (", ""}
+			},
+			expectedFields: []string{"ignoredPodNamespaces[1]", "ignoredPodSelectors[1]", "ignoredPodSelectors[2]"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.Strategy = api.PriorityList
+			},
+			expectedFields: []string{"machineTypePriorities"},
+		},
+		{
+			update: func(conf *api.Config) {
+				conf.Strategy = api.PriorityList
+				conf.MachineTypePriorities = []string{`^m5\.`, "m4.(", " "}
+			},
+			expectedFields: []string{"machineTypePriorities[1]", "machineTypePriorities[2]"},
+		},
+		{
+			update: func(conf *api.Config) {
+				min := 5
+				conf.Schedules = []api.Schedule{
+					{Cron: "0 8 * * mon-fri", Duration: "10h", TimeZone: "Europe/Berlin", WorkersCountMin: &min},
+					{Cron: "0 25 * * *", Duration: "200h", TimeZone: "Mars/Olympus", WorkersCountMin: &min},
+					{Cron: "@daily", Duration: "1h"},
+				}
+			},
+			expectedFields: []string{
+				"schedules[0].workersCountMin",
+				"schedules[1].cron",
+				"schedules[1].duration",
+				"schedules[1].timeZone",
+				"schedules[1].workersCountMin",
+				"schedules[2]",
+			},
+		},
+	}
+
+	for i, tc := range tcs {
+		conf := validTestConfig()
+		tc.update(&conf)
+
+		err := ValidateConfig(conf)
+		if len(tc.expectedFields) == 0 {
+			require.Nilf(t, err, "TC#%d", i+1)
+			continue
+		}
+
+		verr, ok := err.(*api.ValidationError)
+		require.Truef(t, ok, "TC#%d: %v", i+1, err)
+
+		fields := make([]string, 0, len(verr.Errors))
+		for _, e := range verr.Errors {
+			fields = append(fields, e.Field)
+		}
+		require.Equalf(t, tc.expectedFields, fields, "TC#%d", i+1)
+	}
+}
+
+func TestDecodeExternalConfig(t *testing.T) {
+	m := newTestConfigManager(t, api.Config{})
+	require.Nil(t, m.SetConfig(context.Background(), validTestConfig(), ""))
+
+	// own writes should be skipped
+	raw, version, err := m.file.Read()
+	require.Nil(t, err)
+	_, changed, err := m.decode(raw, version)
+	require.Nil(t, err)
+	require.False(t, changed)
+
+	updated := validTestConfig()
+	updated.WorkersCountMax = 5
+	raw, err = json.Marshal(updated)
+	require.Nil(t, err)
+	conf, changed, err := m.decode(raw, file.Version(raw))
+	require.Nil(t, err)
+	require.True(t, changed)
+	require.Equal(t, 5, conf.WorkersCountMax)
+	// current config is changed only after the new one has been applied
+	require.Equal(t, 3, m.GetConfig().WorkersCountMax)
+
+	updated.WorkersCountMin = 10
+	raw, err = json.Marshal(updated)
+	require.Nil(t, err)
+	_, _, err = m.decode(raw, file.Version(raw))
+	_, ok := err.(*api.ValidationError)
+	require.True(t, ok)
+
+	_, _, err = m.decode([]byte("{"), file.Version([]byte("{")))
+	require.NotNil(t, err)
+}
+
+func TestConfigVersion(t *testing.T) {
+	m := newTestConfigManager(t, api.Config{})
+	require.Nil(t, m.SetConfig(context.Background(), validTestConfig(), ""))
+
+	_, version := m.GetConfigVersion()
+	require.NotEmpty(t, version)
+
+	err := m.PatchConfig(context.Background(), []byte(`{"workersCountMax": 5}`), "unknown")
+	require.Equal(t, ErrConflict, errors.Cause(err))
+
+	require.Nil(t, m.PatchConfig(context.Background(), []byte(`{"workersCountMax": 5}`), version))
+	_, newVersion := m.GetConfigVersion()
+	require.NotEqual(t, version, newVersion)
+
+	err = m.SetConfig(context.Background(), validTestConfig(), version)
+	require.Equal(t, ErrConflict, errors.Cause(err))
+
+	// the file has been changed by another writer
+	_, err = m.file.Write([]byte(`{}`), "")
+	require.Nil(t, err)
+	err = m.SetConfig(context.Background(), validTestConfig(), "")
+	require.Equal(t, ErrConflict, errors.Cause(err))
+}
+
+func TestConfigHistory(t *testing.T) {
+	initial := validTestConfig()
+	m := newTestConfigManager(t, initial)
+	ctx := auth.WithUser(context.Background(), &auth.User{Name: "alice"})
+
+	for _, max := range []int{4, 5, 6} {
+		require.Nil(t, m.PatchConfig(ctx, []byte(fmt.Sprintf(`{"workersCountMax": %d}`, max)), ""))
+	}
+
+	// initial config is stored on the first change, the oldest revisions are removed
+	revisions := m.Revisions()
+	require.Len(t, revisions, 3)
+	require.Equal(t, 4, revisions[0].Revision)
+	require.Equal(t, 6, revisions[0].Config.WorkersCountMax)
+	require.Equal(t, "alice", revisions[0].Author)
+	require.Equal(t, 2, revisions[2].Revision)
+
+	require.Nil(t, m.RollbackConfig(ctx, 2, ""))
+	require.Equal(t, 4, m.GetConfig().WorkersCountMax)
+	require.Equal(t, 5, m.Revisions()[0].Revision)
+
+	err := m.RollbackConfig(ctx, 1, "")
+	require.Equal(t, ErrRevisionNotFound, errors.Cause(err))
+
+	// history should be read on start
+	m, err = NewConfigManager(m.file, 3)
+	require.Nil(t, err)
+	require.Equal(t, 5, m.Revisions()[0].Revision)
+	require.Equal(t, "secret", m.Revisions()[0].Config.Provider[aws.SecretKey])
+}
@@ -0,0 +1,187 @@
+package kubescaler
+
+import (
+	"regexp"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+// expander picks a machine type to create for unscheduled pods.
+type expander interface {
+	machineFor(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error)
+}
+
+type expanderFunc func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error)
+
+func (f expanderFunc) machineFor(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+	return f(pods, machineTypes)
+}
+
+// newExpander returns an expander for the strategy, the bigBox one is used by default.
+// Priorities are machine type patterns for the priority strategy.
+func newExpander(strategy api.ScaleUpStrategy, priorities []string) expander {
+	switch strategy {
+	case api.SmallCPUBox:
+		return expanderFunc(func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+			pod := smallestByCPU(pods)
+			cpu, mem := getCPUMemForScheduling(pod)
+			return bestMachineFor(cpu, mem, getScalarForScheduling(pod), machineTypes)
+		})
+	case api.SmallMemBox:
+		return expanderFunc(func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+			pod := smallestByMem(pods)
+			cpu, mem := getCPUMemForScheduling(pod)
+			return bestMachineFor(cpu, mem, getScalarForScheduling(pod), machineTypes)
+		})
+	case api.LeastWaste:
+		return expanderFunc(func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+			return bestPacking(pods, machineTypes, func(a, b packing) bool { return a.waste() < b.waste() })
+		})
+	case api.MostPods:
+		return expanderFunc(func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+			return bestPacking(pods, machineTypes, func(a, b packing) bool { return a.pods > b.pods })
+		})
+	case api.CheapestPerUnit:
+		return expanderFunc(func(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+			return bestPacking(pods, machineTypes, func(a, b packing) bool { return a.unitPrice() < b.unitPrice() })
+		})
+	case api.PriorityList:
+		return newPriorityExpander(priorities)
+	}
+	return expanderFunc(bigBox)
+}
+
+// bigBox picks the cheapest machine type that fits all the pods at once.
+func bigBox(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+	cpu, mem := totalCPUMem(pods)
+	return bestMachineFor(cpu, mem, totalScalar(pods), machineTypes)
+}
+
+// packing is a result of placing pods on a new machine.
+type packing struct {
+	mtype *provider.MachineType
+	// pods is a number of placed pods
+	pods     int
+	cpu, mem resource.Quantity
+	ext      corev1.ResourceList
+}
+
+// waste is a sum of unused cpu and memory shares.
+func (p packing) waste() float64 {
+	return share(p.mtype.CPUResource, p.cpu) + share(p.mtype.MemoryResource, p.mem)
+}
+
+// unitPrice is a price per vCPU and GiB.
+func (p packing) unitPrice() float64 {
+	units := float64(p.mtype.CPUResource.MilliValue())/1000 + float64(p.mtype.MemoryResource.Value())/(1<<30)
+	if units == 0 {
+		return 0
+	}
+	return p.mtype.PriceHour / units
+}
+
+func share(total, free resource.Quantity) float64 {
+	if total.MilliValue() == 0 {
+		return 0
+	}
+	return float64(free.MilliValue()) / float64(total.MilliValue())
+}
+
+// pack places the pods, the largest first, on a new machine of the type.
+func pack(mtype *provider.MachineType, pods []*corev1.Pod) packing {
+	sorted := make([]*corev1.Pod, len(pods))
+	copy(sorted, pods)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		icpu, imem := getCPUMemForScheduling(sorted[i])
+		jcpu, jmem := getCPUMemForScheduling(sorted[j])
+		if c := icpu.Cmp(jcpu); c != 0 {
+			return c > 0
+		}
+		return imem.Cmp(jmem) > 0
+	})
+
+	p := packing{
+		mtype: mtype,
+		cpu:   mtype.CPUResource.DeepCopy(),
+		mem:   mtype.MemoryResource.DeepCopy(),
+		ext:   machineScalar(mtype),
+	}
+	for _, pod := range sorted {
+		cpu, mem := getCPUMemForScheduling(pod)
+		ext := getScalarForScheduling(pod)
+		if p.cpu.Cmp(cpu) < 0 || p.mem.Cmp(mem) < 0 || !fitsScalar(p.ext, ext) {
+			continue
+		}
+		p.cpu.Sub(cpu)
+		p.mem.Sub(mem)
+		subScalar(p.ext, ext)
+		p.pods++
+	}
+	return p
+}
+
+// bestPacking picks a machine type with the best packing of the pods, the cheapest one is
+// taken on a tie. Machine types that can't run any of the pods are skipped.
+func bestPacking(pods []*corev1.Pod, machineTypes []*provider.MachineType, better func(a, b packing) bool) (provider.MachineType, error) {
+	if len(pods) == 0 {
+		return provider.MachineType{}, ErrNoResourcesRequested
+	}
+	if len(machineTypes) == 0 {
+		return provider.MachineType{}, ErrNoAllowedMachines
+	}
+
+	var best *packing
+	// machine types are sorted by price, so the cheapest one is kept on a tie
+	for _, m := range provider.SortedMachineTypes(machineTypes) {
+		p := pack(m, pods)
+		if p.pods == 0 {
+			continue
+		}
+		if best == nil || better(p, *best) {
+			best = &p
+		}
+	}
+	if best == nil {
+		return bigBox(pods, machineTypes)
+	}
+	return *best.mtype, nil
+}
+
+// priorityExpander picks machine types in the order of patterns, the bigBox strategy
+// is used for types matching the same pattern.
+type priorityExpander struct {
+	patterns []*regexp.Regexp
+}
+
+func newPriorityExpander(priorities []string) *priorityExpander {
+	e := &priorityExpander{
+		patterns: make([]*regexp.Regexp, 0, len(priorities)),
+	}
+	for _, p := range priorities {
+		// patterns are checked on validation
+		if re, err := regexp.Compile(p); err == nil {
+			e.patterns = append(e.patterns, re)
+		}
+	}
+	return e
+}
+
+func (e *priorityExpander) machineFor(pods []*corev1.Pod, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+	for _, re := range e.patterns {
+		matched := make([]*provider.MachineType, 0)
+		for _, m := range machineTypes {
+			if re.MatchString(m.Name) && pack(m, pods).pods > 0 {
+				matched = append(matched, m)
+			}
+		}
+		if len(matched) > 0 {
+			return bigBox(pods, matched)
+		}
+	}
+	return bigBox(pods, machineTypes)
+}
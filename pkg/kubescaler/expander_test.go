@@ -0,0 +1,75 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+func TestExpanders(t *testing.T) {
+	pod1CPU4Gi, pod1CPU8Gi := testPod("", "1", "4Gi"), testPod("", "1", "8Gi")
+	pod3CPU8Gi := testPod("", "3", "8Gi")
+
+	tcs := []struct {
+		strategy    api.ScaleUpStrategy
+		priorities  []string
+		pods        []*corev1.Pod
+		expectedVM  provider.MachineType
+		expectedErr error
+	}{
+		{
+			strategy:    api.LeastWaste,
+			expectedErr: ErrNoResourcesRequested,
+		},
+		{
+			strategy:   api.LeastWaste,
+			pods:       []*corev1.Pod{pod1CPU8Gi, pod1CPU8Gi},
+			expectedVM: vmR5LargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.LeastWaste,
+			pods:       []*corev1.Pod{pod1CPU4Gi, pod1CPU4Gi, pod1CPU4Gi, pod1CPU4Gi},
+			expectedVM: vmM4LargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.MostPods,
+			pods:       []*corev1.Pod{pod1CPU4Gi, pod1CPU4Gi, pod1CPU4Gi, pod1CPU4Gi},
+			expectedVM: vmM4xLargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.CheapestPerUnit,
+			pods:       []*corev1.Pod{pod1CPU4Gi},
+			expectedVM: vmR5LargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.PriorityList,
+			priorities: []string{`^r5\.`, `^m4\.`},
+			pods:       []*corev1.Pod{pod3CPU8Gi},
+			expectedVM: vmR5xLargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.PriorityList,
+			priorities: []string{`^c5\.`},
+			pods:       []*corev1.Pod{pod3CPU8Gi},
+			expectedVM: vmM4xLargePrice02CPU2Mem4G,
+		},
+		{
+			strategy:   api.BigBox,
+			pods:       []*corev1.Pod{pod1CPU8Gi, pod1CPU8Gi},
+			expectedVM: vmR5LargePrice02CPU2Mem4G,
+		},
+	}
+
+	vmTypes := []*provider.MachineType{&vmM4LargePrice02CPU2Mem4G, &vmM4xLargePrice02CPU2Mem4G, &vmM42xLargePrice02CPU2Mem4G,
+		&vmR5LargePrice02CPU2Mem4G, &vmR5xLargePrice02CPU2Mem4G, &vmR52xLargePrice02CPU2Mem4G}
+	for i, tc := range tcs {
+		mtype, err := newExpander(tc.strategy, tc.priorities).machineFor(tc.pods, vmTypes)
+		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d: check error", i+1)
+		require.Equalf(t, tc.expectedVM, mtype, "TC#%d: check machine type", i+1)
+	}
+}
@@ -0,0 +1,139 @@
+package kubescaler
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+// headroomPods returns virtual pods of the headroom. Pods that can't fit on any of the allowed
+// machine types are skipped, otherwise workers would be created for them on every scan.
+func headroomPods(h *api.Headroom, machineTypes []*provider.MachineType) []*corev1.Pod {
+	if h == nil {
+		return nil
+	}
+
+	requests := corev1.ResourceList{}
+	// quantities are checked on validation
+	if q, err := resource.ParseQuantity(h.CPU); err == nil {
+		requests[corev1.ResourceCPU] = q
+	}
+	if q, err := resource.ParseQuantity(h.Memory); err == nil {
+		requests[corev1.ResourceMemory] = q
+	}
+
+	n := h.Pods
+	if n == 0 {
+		n = 1
+	}
+	pods := make([]*corev1.Pod, 0, n)
+	for i := 0; i < n; i++ {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("headroom-%d", i),
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Resources: corev1.ResourceRequirements{Requests: requests},
+					},
+				},
+			},
+		}
+		if !hasMachineFor(machineTypes, pod) {
+			return nil
+		}
+		pods = append(pods, pod)
+	}
+	return pods
+}
+
+// unfitPods returns virtual pods that don't fit on free resources of the nodes, the excluded
+// and unschedulable nodes aren't taken into account. Pods are placed with a first fit.
+func unfitPods(virtual []*corev1.Pod, nodes []*corev1.Node, scheduledPods []*corev1.Pod, excluded map[string]bool) []*corev1.Pod {
+	if len(virtual) == 0 {
+		return nil
+	}
+
+	type free struct {
+		cpu, mem resource.Quantity
+	}
+	nodeFree := make([]*free, 0, len(nodes))
+	nodeIdx := make(map[string]*free, len(nodes))
+	for _, node := range nodes {
+		if excluded[node.Name] || !schedulable(node) {
+			continue
+		}
+		f := &free{
+			cpu: *node.Status.Allocatable.Cpu(),
+			mem: *node.Status.Allocatable.Memory(),
+		}
+		nodeFree = append(nodeFree, f)
+		nodeIdx[node.Name] = f
+	}
+	for _, pod := range scheduledPods {
+		if f := nodeIdx[pod.Spec.NodeName]; f != nil {
+			cpu, mem := getCPUMemForScheduling(pod)
+			f.cpu.Sub(cpu)
+			f.mem.Sub(mem)
+		}
+	}
+
+	unfit := make([]*corev1.Pod, 0)
+	for _, pod := range virtual {
+		cpu, mem := getCPUMemForScheduling(pod)
+		placed := false
+		for _, f := range nodeFree {
+			if f.cpu.Cmp(cpu) >= 0 && f.mem.Cmp(mem) >= 0 {
+				f.cpu.Sub(cpu)
+				f.mem.Sub(mem)
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			unfit = append(unfit, pod)
+		}
+	}
+	return unfit
+}
+
+// headroomKeeper returns a function that reports whether a worker should be kept to provide
+// the headroom. Workers that are allowed to be removed are taken into account on next calls.
+func headroomKeeper(virtual []*corev1.Pod, nodes []*corev1.Node, scheduledPods []*corev1.Pod) func(w *api.Worker) bool {
+	removed := make(map[string]bool)
+	return func(w *api.Worker) bool {
+		if len(virtual) == 0 {
+			return false
+		}
+
+		excluded := map[string]bool{w.NodeName: true}
+		for name := range removed {
+			excluded[name] = true
+		}
+		if len(unfitPods(virtual, nodes, scheduledPods, excluded)) > 0 {
+			return true
+		}
+		removed[w.NodeName] = true
+		return false
+	}
+}
+
+// schedulable returns false for cordoned nodes and nodes with NoSchedule or NoExecute taints,
+// virtual pods have no tolerations.
+func schedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == corev1.TaintEffectNoSchedule || taint.Effect == corev1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	return true
+}
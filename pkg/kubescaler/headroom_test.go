@@ -0,0 +1,134 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/kubernetes/filters"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+func testNode(name, cpu, mem string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(mem),
+			},
+		},
+	}
+}
+
+func testPod(nodeName, cpu, mem string) *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{
+				{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse(cpu),
+							corev1.ResourceMemory: resource.MustParse(mem),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestHeadroom(t *testing.T) {
+	machineTypes := []*provider.MachineType{
+		{Name: "m4.large", CPUResource: resource.MustParse("2"), MemoryResource: resource.MustParse("8Gi")},
+	}
+	nodes := []*corev1.Node{
+		testNode("a", "2", "8Gi"),
+		testNode("b", "2", "8Gi"),
+	}
+	pods := []*corev1.Pod{
+		testPod("a", "1500m", "1Gi"),
+	}
+
+	tcs := []struct {
+		headroom      *api.Headroom
+		excluded      map[string]bool
+		expectedPods  int
+		expectedUnfit int
+	}{
+		{},
+		{
+			headroom:     &api.Headroom{CPU: "1", Memory: "1Gi"},
+			expectedPods: 1,
+		},
+		{
+			headroom:      &api.Headroom{CPU: "1", Pods: 3},
+			expectedPods:  3,
+			expectedUnfit: 1,
+		},
+		{
+			headroom:      &api.Headroom{CPU: "500m", Pods: 3},
+			excluded:      map[string]bool{"b": true},
+			expectedPods:  3,
+			expectedUnfit: 2,
+		},
+		{
+			// can't fit on any machine type
+			headroom: &api.Headroom{CPU: "4"},
+		},
+	}
+
+	for i, tc := range tcs {
+		virtual := headroomPods(tc.headroom, machineTypes)
+		require.Lenf(t, virtual, tc.expectedPods, "TC#%d", i+1)
+		require.Lenf(t, unfitPods(virtual, nodes, pods, tc.excluded), tc.expectedUnfit, "TC#%d", i+1)
+	}
+
+	// tainted nodes have no room for virtual pods
+	tainted := append(nodes, testNode("c", "2", "8Gi"))
+	tainted[2].Spec.Taints = []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}
+	require.Len(t, unfitPods(headroomPods(&api.Headroom{CPU: "1", Pods: 3}, machineTypes), tainted, pods, nil), 1)
+
+	// one of empty nodes should be kept
+	nodes = append(nodes, testNode("c", "2", "8Gi"))
+	keep := headroomKeeper(headroomPods(&api.Headroom{CPU: "1", Pods: 2}, machineTypes), nodes, pods)
+	require.False(t, keep(&api.Worker{NodeName: "b"}))
+	require.True(t, keep(&api.Worker{NodeName: "c"}))
+}
+
+func TestFilterOutMasters(t *testing.T) {
+	master := testNode("master", "2", "8Gi")
+	master.Labels = map[string]string{"node-role.kubernetes.io/master": ""}
+	master.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+	notReadyMaster := master.DeepCopy()
+	notReadyMaster.Status.Conditions[0].Status = corev1.ConditionFalse
+	worker := testNode("worker", "2", "8Gi")
+	worker.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+
+	tcs := []struct {
+		nodes    []*corev1.Node
+		expected []string
+	}{
+		{
+			expected: []string{},
+		},
+		{
+			// NotReady master and no ready workers
+			nodes:    []*corev1.Node{notReadyMaster},
+			expected: []string{},
+		},
+		{
+			nodes:    []*corev1.Node{master, worker},
+			expected: []string{"worker"},
+		},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expected, nodeNames(filterOutMasters(filters.GetReadyNodes(tc.nodes))), "TC#%d", i+1)
+	}
+}
@@ -0,0 +1,110 @@
+package kubescaler
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/log"
+	"github.com/supergiant/capacity/pkg/persistentfile"
+)
+
+const (
+	// DefaultHistoryLimit is a number of config revisions to keep.
+	DefaultHistoryLimit = 10
+
+	historyFileName = "history"
+)
+
+var ErrRevisionNotFound = errors.New("config revision not found")
+
+// configHistory keeps the last applied config revisions, the oldest ones are removed.
+type configHistory struct {
+	file  persistentfile.Interface
+	limit int
+
+	mu        sync.RWMutex
+	revisions []api.ConfigRevision
+}
+
+func newConfigHistory(file persistentfile.Interface, limit int) *configHistory {
+	h := &configHistory{
+		file:  file,
+		limit: limit,
+	}
+
+	raw, _, err := file.Read()
+	if err != nil {
+		if !persistentfile.IsNotExist(err) {
+			log.Warnf("kubescaler: read config history from %s: %v", file.Info(), err)
+		}
+		return h
+	}
+	if err = json.Unmarshal(raw, &h.revisions); err != nil {
+		log.Warnf("kubescaler: decode config history from %s: %v", file.Info(), err)
+	}
+	return h
+}
+
+// add stores a new revision. The previous config is stored first if the history
+// is empty, so it's possible to roll back the first change.
+func (h *configHistory) add(conf api.Config, author string, prev api.Config) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.revisions) == 0 && prev.ClusterName != "" {
+		h.revisions = append(h.revisions, api.ConfigRevision{
+			Revision:  1,
+			Timestamp: time.Now(),
+			Config:    prev,
+		})
+	}
+
+	last := 0
+	if len(h.revisions) > 0 {
+		last = h.revisions[len(h.revisions)-1].Revision
+	}
+	h.revisions = append(h.revisions, api.ConfigRevision{
+		Revision:  last + 1,
+		Author:    author,
+		Timestamp: time.Now(),
+		Config:    conf,
+	})
+	if len(h.revisions) > h.limit {
+		h.revisions = h.revisions[len(h.revisions)-h.limit:]
+	}
+
+	raw, err := json.Marshal(h.revisions)
+	if err != nil {
+		return errors.Wrap(err, "encode config history")
+	}
+	_, err = h.file.Write(raw, "")
+	return errors.Wrapf(err, "write config history to %s", h.file.Info())
+}
+
+// list returns revisions starting from the latest one.
+func (h *configHistory) list() []api.ConfigRevision {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	out := make([]api.ConfigRevision, 0, len(h.revisions))
+	for i := len(h.revisions) - 1; i >= 0; i-- {
+		out = append(out, h.revisions[i])
+	}
+	return out
+}
+
+func (h *configHistory) get(revision int) (api.ConfigRevision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, r := range h.revisions {
+		if r.Revision == revision {
+			return r, nil
+		}
+	}
+	return api.ConfigRevision{}, errors.Wrapf(ErrRevisionNotFound, "revision %d", revision)
+}
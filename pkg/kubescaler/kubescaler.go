@@ -13,10 +13,8 @@ import (
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kubeversion "k8s.io/apimachinery/pkg/version"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
-	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 
 	"github.com/supergiant/capacity/pkg/api"
@@ -38,6 +36,13 @@ const (
 
 	// defaultNodeTimeBuffer is a time in seconds to wait for node is in Ready state.
 	defaultNodeTimeBuffer = 60
+
+	// providerCheckTimeout limits a provider request made to check a new config.
+	providerCheckTimeout = 30 * time.Second
+
+	// machines in other states are being stopped or terminated
+	machineStatePending = "pending"
+	machineStateRunning = "running"
 )
 
 var (
@@ -48,6 +53,16 @@ var (
 	DefaultMaxMachineProvisionTime = time.Minute * 10
 )
 
+// ApplyError is returned if a worker manager can't be built for a new config,
+// the config isn't persisted in that case.
+type ApplyError struct {
+	Err error
+}
+
+func (e *ApplyError) Error() string {
+	return "apply config: " + e.Err.Error()
+}
+
 type ListerRegistry interface {
 	ReadyNodeLister() listers.NodeLister
 	ScheduledPodLister() listers.PodLister
@@ -58,7 +73,17 @@ type Options struct {
 	ConfigFile         string
 	ConfigMapName      string
 	ConfigMapNamespace string
-	Kubeconfig         string
+	// ConfigMapKey is a ConfigMap key with the config, its suffix defines a config format,
+	// e.g. 'kubescaler.yaml'.
+	ConfigMapKey string
+	Kubeconfig   string
+	// ConfigResource is a CapacityConfig resource to use instead of a config file,
+	// pass as a namespace/name.
+	ConfigResource string
+	// ConfigHistoryLimit is a number of config revisions to keep.
+	ConfigHistoryLimit int
+	// WorkerResources enables Worker resources that mirror machines.
+	WorkerResources bool
 }
 
 type Kubescaler struct {
@@ -66,11 +91,21 @@ type Kubescaler struct {
 	kclient        corev1client.CoreV1Interface
 	listerRegistry listers.Registry
 
+	// applyMutex serializes config updates
+	applyMutex    sync.Mutex
 	configManager *ConfigManager
+	secrets       *secretResolver
+
+	status scalerStatus
+	// backoff keeps machine types that have failed to create workers
+	backoff machineBackoff
+	// workerMirror is nil if Worker resources are disabled
+	workerMirror *workers.Mirror
 
-	workerMutex   sync.RWMutex
-	isReady       bool
-	workerManager workers.WInterface
+	workerMutex    sync.RWMutex
+	isReady        bool
+	workerManager  workers.WInterface
+	secretVersions map[string]string
 }
 
 func New(opts Options) (*Kubescaler, error) {
@@ -86,7 +121,7 @@ func New(opts Options) (*Kubescaler, error) {
 	}
 	log.Infof("kubescaler: get config from: %s", f.Info())
 
-	conf, err := NewConfigManager(f)
+	conf, err := NewConfigManager(f, opts.ConfigHistoryLimit)
 	if err != nil {
 		return nil, errors.Wrap(err, "setup persistent config")
 	}
@@ -94,15 +129,22 @@ func New(opts Options) (*Kubescaler, error) {
 	kubeScaler := &Kubescaler{
 		kclient:        kclient,
 		configManager:  conf,
+		secrets:        newSecretResolver(kclient, opts.ConfigMapNamespace),
 		stopCh:         make(chan struct{}),
 		listerRegistry: listers.NewRegistryWithDefaultListers(kclient.RESTClient(), nil),
 	}
+	if opts.WorkerResources {
+		kubeScaler.workerMirror = workers.NewMirror(kclient.RESTClient())
+	}
 
 	// We skip this error because on this stage capacity service may not be
 	// configured
-	if err := kubeScaler.buildWorkerManager(); err != nil {
-		log.Infof("kubescaler was not configured yet, to configure " +
-			"make POST request to /api/v1/config Handler with valid configHandler object")
+	workerManager, secretVersions, err := kubeScaler.buildWorkerManager(conf.GetConfig())
+	if err != nil {
+		log.Infof("kubescaler was not configured yet, to configure "+
+			"make POST request to /api/v1/config Handler with valid configHandler object: %v", err)
+	} else {
+		kubeScaler.setWorkerManager(workerManager, secretVersions)
 	}
 
 	return kubeScaler, nil
@@ -118,17 +160,36 @@ func (s *Kubescaler) Run() error {
 		log.Info("Automatic Capacity will occur unless paused.")
 	}
 
+	watchStopCh := make(chan struct{})
+	defer close(watchStopCh)
+	if err := s.configManager.watch(watchStopCh, s.reloadConfigWithStatus); err != nil {
+		// the service still can be configured through the API
+		log.Errorf("kubescaler: %v", err)
+	}
+
 	func() {
 		for {
 			select {
 			case <-time.After(DefaultScanInterval):
 				{
 					if !s.IsReady() {
-						continue
+						if err := s.retryConfig(); err != nil {
+							log.Errorf("kubescaler: %v", err)
+						}
+						if !s.IsReady() {
+							continue
+						}
+					}
+					if err := s.reloadOnSecretsChange(); err != nil {
+						log.Errorf("kubescaler: %v", err)
 					}
-					if err := s.RunOnce(time.Now()); err != nil {
+					err := s.RunOnce(time.Now())
+					if err != nil {
 						log.Errorf("kubescaler: %v", err)
 					}
+					s.status.setRunErr(err)
+					s.writeStatus()
+					s.syncWorkerResources()
 				}
 			case <-s.stopCh:
 				return
@@ -139,6 +200,22 @@ func (s *Kubescaler) Run() error {
 	return nil
 }
 
+// syncWorkerResources updates Worker resources, it's done even if the service is paused
+// to keep them useful for monitoring.
+func (s *Kubescaler) syncWorkerResources() {
+	if s.workerMirror == nil {
+		return
+	}
+
+	s.workerMutex.RLock()
+	workerManager := s.workerManager
+	s.workerMutex.RUnlock()
+
+	if err := s.workerMirror.Sync(context.Background(), workerManager); err != nil {
+		log.Errorf("kubescaler: sync worker resources: %v", err)
+	}
+}
+
 func (s *Kubescaler) Stop(ctx context.Context) error {
 	done := make(chan struct{})
 	go func() {
@@ -158,7 +235,12 @@ func (s *Kubescaler) Stop(ctx context.Context) error {
 }
 
 func (s *Kubescaler) RunOnce(currentTime time.Time) error {
-	cfg := s.configManager.GetConfig()
+	cfg, schedule := s.configManager.GetConfig().Scheduled(currentTime)
+	if schedule != nil {
+		log.Debugf("kubescaler: schedule %q (%s) is active: workersCountMin=%d, workersCountMax=%d",
+			schedule.Name, schedule.Cron, cfg.WorkersCountMin, cfg.WorkersCountMax)
+	}
+	s.status.setLimits(cfg, schedule, currentTime)
 
 	//Paused defaults to false if omitted.
 	paused := cfg.Paused != nil && *(cfg.Paused)
@@ -183,6 +265,12 @@ func (s *Kubescaler) RunOnce(currentTime time.Time) error {
 		return err
 	}
 
+	count, ready := countWorkers(rss.workerList)
+	shortfall := 0
+	if cfg.WorkersCountMin > count {
+		shortfall = cfg.WorkersCountMin - count
+	}
+	s.status.setWorkers(count, ready, shortfall)
 	log.Debugf("kubescaler: rss: unscheduledPods=%v", podNames(rss.unscheduledPods))
 
 	failed, provisioning := s.checkWorkers(rss.workerList, currentTime)
@@ -198,16 +286,45 @@ func (s *Kubescaler) RunOnce(currentTime time.Time) error {
 		return nil
 	}
 
+	if shortfall > 0 {
+		// don't wait for unscheduled pods, e.g. workers have been deleted or a schedule has raised the minimum
+		log.Infof("kubescaler: run: %d worker(s) missing to meet workersCountMin(%d)", shortfall, cfg.WorkersCountMin)
+		if err = s.scaleToMin(shortfall, cfg.DefaultMachineType, allowedMachineTypes, currentTime); err != nil {
+			return errors.Wrap(err, "scale up")
+		}
+		return nil
+	}
+
+	// machine types are sized by resources available for pods on their nodes
+	nodes := filterOutMasters(rss.readyNodes)
+	allowedMachineTypes = allocatableMachines(allowedMachineTypes, cfg, rss.workerList, nodes, rss.allPods)
+
+	filter := newPodFilter(cfg)
+	if zones := s.Zones(); len(zones) > 0 {
+		// pods with zonal volumes need workers in the same zone, ignored pods aren't checked
+		candidates, _ := filterPods(rss.unscheduledPods, filter, allowedMachineTypes, currentTime)
+		filter = filter.withZones(zones, s.volumeZones(candidates))
+	}
+
+	// headroom pods are kept pending until there is room for them on ready nodes
+	virtual := headroomPods(cfg.Headroom, allowedMachineTypes)
+	headroom := unfitPods(virtual, nodes, rss.scheduledPods, nil)
+	if len(headroom) > 0 {
+		log.Debugf("kubescaler: headroom: %d of %d virtual pods don't fit on ready nodes", len(headroom), len(virtual))
+	}
+
 	if len(rss.unscheduledPods) > 0 {
 		if emptyNodes := getEmptyNodes(rss.readyNodes, rss.allPods); len(emptyNodes) > 0 {
 			log.Debugf("kubescaler: scale up: there are %v ready empty nodes in the cluster", nodeNames(emptyNodes))
 			return nil
 		}
+	}
 
+	if len(rss.unscheduledPods) > 0 || len(headroom) > 0 {
 		if cfg.WorkersCountMax > 0 && cfg.WorkersCountMax > len(rss.workerList.Items) {
 			var scaled bool
 			// try to scale up the cluster. In case of success no need to scale down
-			scaled, err = s.scaleUp(rss.unscheduledPods, allowedMachineTypes, cfg.Strategy, currentTime)
+			scaled, err = s.scaleUp(rss.unscheduledPods, headroom, filter, allowedMachineTypes, cfg.Strategy, cfg.MachineTypePriorities, currentTime)
 			if err != nil {
 				return errors.Wrap(err, "scale up")
 			}
@@ -220,13 +337,17 @@ func (s *Kubescaler) RunOnce(currentTime time.Time) error {
 		}
 	}
 
-	if cfg.WorkersCountMin > 0 && cfg.WorkersCountMin < len(rss.workerList.Items) {
-		if err = s.scaleDown(rss.scheduledPods, rss.workerList, cfg.IgnoredNodeLabels, cfg.WorkersLifespanMinutes, currentTime); err != nil {
+	if cfg.WorkersCountMin > 0 && cfg.WorkersCountMin < count {
+		limit := count - cfg.WorkersCountMin
+		keep := headroomKeeper(virtual, nodes, rss.scheduledPods)
+		// low priority pods aren't a load, they can be rescheduled or preempted
+		scheduledPods := filter.filterOutLowPriority(rss.scheduledPods)
+		if err = s.scaleDown(scheduledPods, rss.workerList, limit, keep, cfg.IgnoredNodeLabels, cfg.WorkersLifespanMinutes, currentTime); err != nil {
 			return errors.Wrap(err, "scale down")
 		}
 	} else {
-		log.Debugf("kubescaler: scaledown: workersCountMin(%d) < number of workers(%d), skipping..",
-			cfg.WorkersCountMin, len(rss.workerList.Items))
+		log.Debugf("kubescaler: scaledown: workersCountMin(%d) >= number of workers(%d), skipping..",
+			cfg.WorkersCountMin, count)
 	}
 
 	return nil
@@ -285,7 +406,7 @@ func (s *Kubescaler) checkWorkers(workerList *api.WorkerList, currentTime time.T
 	failed := make([]string, 0)
 
 	for _, worker := range workerList.Items {
-		ignored := !(worker.MachineState == "pending" || worker.MachineState == "running") ||
+		ignored := !(worker.MachineState == machineStatePending || worker.MachineState == machineStateRunning) ||
 			worker.NodeState == workers.NodeStateReady ||
 			isMaster(worker)
 
@@ -355,27 +476,15 @@ func nodeNames(nodes []*corev1.Node) []string {
 	return list
 }
 
-const (
-	nodeLabelRole   = "kubernetes.io/role"
-	nodeLabelMaster = "master"
-)
-
-func filterOutMasters(nodes []*corev1.Node, pods []*corev1.Pod) []*corev1.Node {
-	masters := make(map[string]bool)
-	for _, pod := range pods {
-		if pod.Namespace == metav1.NamespaceSystem && pod.Labels[nodeLabelRole] == nodeLabelMaster {
-			masters[pod.Spec.NodeName] = true
-		}
-	}
-
-	// if masters aren't on the list of nodes, capacity will be increased on overflowing append
-	others := make([]*corev1.Node, 0, len(nodes)-len(masters))
+// filterOutMasters returns nodes without the master role label, the same rule is used
+// to filter out masters from the list of workers.
+func filterOutMasters(nodes []*corev1.Node) []*corev1.Node {
+	others := make([]*corev1.Node, 0, len(nodes))
 	for _, node := range nodes {
-		if !masters[node.Name] {
+		if !workers.IsMasterNode(node) {
 			others = append(others, node)
 		}
 	}
-
 	return others
 }
 
@@ -393,12 +502,26 @@ func getEmptyNodes(nodes []*corev1.Node, pods []*corev1.Pod) []*corev1.Node {
 
 // getConfigFile tries to locate the kubescaler config file.
 // Sources priority order:
+//   - CapacityConfig resource, if provided;
 //   - file on the provided path;
 //   - configmap;
 //   - file on the default path.
 //
 // TODO: pass only configfile options
-func getConfigFile(opts Options, cmGetter v1.ConfigMapsGetter) (persistentfile.Interface, error) {
+func getConfigFile(opts Options, kclient corev1client.CoreV1Interface) (persistentfile.Interface, error) {
+	if opts.ConfigResource != "" {
+		ns, name := opts.ConfigMapNamespace, opts.ConfigResource
+		if parts := strings.SplitN(opts.ConfigResource, "/", 2); len(parts) == 2 {
+			ns, name = parts[0], parts[1]
+		}
+		return persistentfile.New(persistentfile.Config{
+			Type:              persistentfile.ResourceFile,
+			ResourceName:      name,
+			ResourceNamespace: ns,
+			RESTClient:        kclient.RESTClient(),
+		})
+	}
+
 	// try to use a file on provided path
 	f, err := persistentfile.New(persistentfile.Config{
 		Type: persistentfile.FSFile,
@@ -410,12 +533,16 @@ func getConfigFile(opts Options, cmGetter v1.ConfigMapsGetter) (persistentfile.I
 	}
 
 	// try to setup a configMap file
+	key := opts.ConfigMapKey
+	if key == "" {
+		key = api.DefaultConfigMapKey
+	}
 	f, err = persistentfile.New(persistentfile.Config{
 		Type:               persistentfile.ConfigMapFile,
 		ConfigMapName:      opts.ConfigMapName,
 		ConfigMapNamespace: opts.ConfigMapNamespace,
-		Key:                api.DefaultConfigMapKey,
-		ConfigMapClient:    cmGetter,
+		Key:                key,
+		ConfigMapClient:    kclient,
 	})
 	if err == nil {
 		return f, nil
@@ -446,6 +573,18 @@ func (s *Kubescaler) CreateWorker(ctx context.Context, mtype string) (*api.Worke
 	return s.workerManager.CreateWorker(ctx, mtype)
 }
 
+func (s *Kubescaler) CreateWorkerInZone(ctx context.Context, mtype, zone string) (*api.Worker, error) {
+	s.workerMutex.RLock()
+	defer s.workerMutex.RUnlock()
+	return s.workerManager.CreateWorkerInZone(ctx, mtype, zone)
+}
+
+func (s *Kubescaler) Zones() []string {
+	s.workerMutex.RLock()
+	defer s.workerMutex.RUnlock()
+	return s.workerManager.Zones()
+}
+
 func (s *Kubescaler) GetWorker(ctx context.Context, id string) (*api.Worker, error) {
 	s.workerMutex.RLock()
 	defer s.workerMutex.RUnlock()
@@ -470,31 +609,68 @@ func (s *Kubescaler) ReserveWorker(ctx context.Context, worker *api.Worker) (*ap
 	return s.workerManager.ReserveWorker(ctx, worker)
 }
 
-func (s *Kubescaler) SetConfig(conf api.Config) error {
-	// Recreate worker manager on config update
-	if err := s.configManager.SetConfig(conf); err != nil {
+// SetConfig builds a worker manager for the new config and persists the config only
+// if it succeeds, otherwise the current worker manager is kept. A not empty version
+// should match the current config version.
+func (s *Kubescaler) SetConfig(ctx context.Context, conf api.Config, version string) error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	if err := s.configManager.checkVersion(version); err != nil {
 		return err
 	}
-
-	s.workerMutex.Lock()
-	defer s.workerMutex.Unlock()
-
-	return s.buildWorkerManager()
+	conf, err := s.configManager.prepare(conf)
+	if err != nil {
+		return err
+	}
+	return s.applyConfig(conf, authorFrom(ctx))
 }
 
 func (s *Kubescaler) GetConfig() api.Config {
 	return s.configManager.GetConfig()
 }
 
-func (s *Kubescaler) PatchConfig(conf api.Config) error {
-	if err := s.configManager.PatchConfig(conf); err != nil {
+// GetConfigVersion returns the current config and its version.
+func (s *Kubescaler) GetConfigVersion() (api.Config, string) {
+	return s.configManager.GetConfigVersion()
+}
+
+// PatchConfig applies a JSON Merge Patch to the current config, see SetConfig.
+func (s *Kubescaler) PatchConfig(ctx context.Context, patch []byte, version string) error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	if err := s.configManager.checkVersion(version); err != nil {
+		return err
+	}
+	conf, err := s.configManager.patched(patch)
+	if err != nil {
+		return err
+	}
+	if conf, err = s.configManager.prepare(conf); err != nil {
 		return err
 	}
+	return s.applyConfig(conf, authorFrom(ctx))
+}
 
-	s.workerMutex.Lock()
-	defer s.workerMutex.Unlock()
+// RollbackConfig applies the config of the provided revision, see SetConfig.
+func (s *Kubescaler) RollbackConfig(ctx context.Context, revision int, version string) error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	if err := s.configManager.checkVersion(version); err != nil {
+		return err
+	}
+	conf, err := s.configManager.revisionConfig(revision)
+	if err != nil {
+		return err
+	}
+	return s.applyConfig(conf, authorFrom(ctx))
+}
 
-	return s.buildWorkerManager()
+// ConfigRevisions returns stored config revisions starting from the latest one.
+func (s *Kubescaler) ConfigRevisions() []api.ConfigRevision {
+	return s.configManager.Revisions()
 }
 
 func (s *Kubescaler) IsReady() bool {
@@ -503,35 +679,151 @@ func (s *Kubescaler) IsReady() bool {
 	return s.isReady
 }
 
-func (s *Kubescaler) buildWorkerManager() error {
-	cfg := s.configManager.GetConfig()
+// applyConfig builds and checks a worker manager for the config, persists the config
+// and replaces the current worker manager. Nothing is changed on error.
+func (s *Kubescaler) applyConfig(conf api.Config, author string) error {
+	workerManager, secretVersions, err := s.buildWorkerManager(conf)
+	if err != nil {
+		return &ApplyError{Err: err}
+	}
+
+	if err = s.configManager.store(conf, author); err != nil {
+		return err
+	}
+
+	s.setWorkerManager(workerManager, secretVersions)
+	s.status.setConfigErr(nil)
+	return nil
+}
+
+// reloadConfigWithStatus reports the result of the config reload, so rejected external
+// changes are visible on the CapacityConfig resource status.
+func (s *Kubescaler) reloadConfigWithStatus(raw []byte, version string) error {
+	err := s.reloadConfig(raw, version)
+	s.status.setConfigErr(err)
+	s.writeStatus()
+	return err
+}
+
+// reloadConfig applies the config file changed externally (e.g. with kubectl). The file
+// isn't rewritten and the current config is kept if the new one can't be applied.
+func (s *Kubescaler) reloadConfig(raw []byte, version string) error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	conf, changed, err := s.configManager.decode(raw, version)
+	if err != nil || !changed {
+		return err
+	}
+
+	log.Infof("kubescaler: config has been changed externally, rebuild worker manager")
+	workerManager, secretVersions, err := s.buildWorkerManager(conf)
+	if err != nil {
+		return &ApplyError{Err: err}
+	}
+
+	s.configManager.setCurrent(conf, raw, version, "")
+	s.setWorkerManager(workerManager, secretVersions)
+	return nil
+}
+
+// retryConfig builds a worker manager for the current config if it has failed before,
+// e.g. a referenced Secret has been created after the service was started.
+func (s *Kubescaler) retryConfig() error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	conf := s.configManager.GetConfig()
+	if s.IsReady() || conf.ProviderName == "" {
+		return nil
+	}
+
+	workerManager, secretVersions, err := s.buildWorkerManager(conf)
+	s.status.setConfigErr(err)
+	s.writeStatus()
+	if err != nil {
+		return errors.Wrap(err, "kubescaler is not ready")
+	}
+
+	log.Infof("kubescaler: worker manager has been built for the current config")
+	s.setWorkerManager(workerManager, secretVersions)
+	return nil
+}
+
+// reloadOnSecretsChange rebuilds the worker manager if any of the Secrets referenced
+// by the config has been changed.
+func (s *Kubescaler) reloadOnSecretsChange() error {
+	s.applyMutex.Lock()
+	defer s.applyMutex.Unlock()
+
+	s.workerMutex.RLock()
+	versions := s.secretVersions
+	s.workerMutex.RUnlock()
+
+	changed, err := s.secrets.changed(versions)
+	if err != nil {
+		return errors.Wrap(err, "check referenced secrets")
+	}
+	if !changed {
+		return nil
+	}
+
+	log.Infof("kubescaler: referenced secrets have been changed, rebuild worker manager")
+	workerManager, secretVersions, err := s.buildWorkerManager(s.configManager.GetConfig())
+	if err != nil {
+		return errors.Wrap(err, "keep the current worker manager")
+	}
+	s.setWorkerManager(workerManager, secretVersions)
+	return nil
+}
+
+func (s *Kubescaler) setWorkerManager(workerManager workers.WInterface, secretVersions map[string]string) {
+	s.workerMutex.Lock()
+	defer s.workerMutex.Unlock()
+
+	s.workerManager = workerManager
+	s.secretVersions = secretVersions
+	s.isReady = true
+}
+
+// buildWorkerManager creates a worker manager for the config and checks the provider
+// is able to list machines. It doesn't change the kubescaler state.
+func (s *Kubescaler) buildWorkerManager(conf api.Config) (workers.WInterface, map[string]string, error) {
+	cfg, secretVersions, err := s.secrets.resolve(conf)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "resolve config references")
+	}
 
 	vmProvider, err := factory.New(cfg.ClusterName, cfg.ProviderName, cfg.Provider)
 	if err != nil {
-		return errors.Wrapf(err, "build vm provider")
+		return nil, nil, errors.Wrapf(err, "build vm provider")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerCheckTimeout)
+	defer cancel()
+	if _, err = vmProvider.Machines(ctx); err != nil {
+		return nil, nil, errors.Wrap(err, "check vm provider")
 	}
 
 	if cfg.SupergiantV1Config != nil {
 		v, err := getServerVersion(s.kclient.RESTClient())
 		if err != nil {
-			return errors.Wrapf(err, "build kubernetes client")
+			return nil, nil, errors.Wrapf(err, "build kubernetes client")
 		}
 		cfg.SupergiantV1Config.KubeVersion = v.String()
 	}
 	userdata, err := buildUserdata(cfg)
 	if err != nil {
-		return errors.Wrap(err, "build userdata")
+		return nil, nil, errors.Wrap(err, "build userdata")
 	}
 
 	log.Infof("Create new worker manager for cluster %s", cfg.ClusterName)
 	workerManager, err := workers.NewManager(cfg.ClusterName, s.kclient.Nodes(), vmProvider, userdata)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	s.workerManager = workerManager
-	s.isReady = true
-	return nil
+	return workerManager, secretVersions, nil
 }
 
 func buildUserdata(cfg api.Config) (string, error) {
@@ -0,0 +1,55 @@
+package kubescaler
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Supported patch types:
+const (
+	MergePatchType = "application/merge-patch+json"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// mergePatch applies a JSON Merge Patch to the document as described in the RFC 7386.
+//   - patch object members replace the document ones;
+//   - null members remove the document ones;
+//   - objects are merged recursively, other values (including arrays) are replaced.
+func mergePatch(doc, patch []byte) ([]byte, error) {
+	var patchVal interface{}
+	if err := json.Unmarshal(patch, &patchVal); err != nil {
+		return nil, errors.Wrap(ErrInvalidPatch, err.Error())
+	}
+
+	var docVal interface{}
+	if len(doc) > 0 {
+		if err := json.Unmarshal(doc, &docVal); err != nil {
+			return nil, errors.Wrap(err, "decode document")
+		}
+	}
+
+	return json.Marshal(mergeValue(docVal, patchVal))
+}
+
+func mergeValue(doc, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		docObj = make(map[string]interface{})
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(docObj, k)
+			continue
+		}
+		docObj[k] = mergeValue(docObj[k], v)
+	}
+	return docObj
+}
@@ -0,0 +1,153 @@
+package kubescaler
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+// isExtendedResource reports whether the resource is an extended one, e.g. 'nvidia.com/gpu'.
+// Native resources have no domain or the kubernetes.io one.
+func isExtendedResource(name corev1.ResourceName) bool {
+	s := string(name)
+	return strings.Contains(s, "/") && !strings.Contains(s, corev1.ResourceDefaultNamespacePrefix) &&
+		!strings.HasPrefix(s, corev1.DefaultResourceRequestsPrefix)
+}
+
+// isScalarResource reports whether the resource is checked on scheduling along with cpu and memory.
+func isScalarResource(name corev1.ResourceName) bool {
+	return name == corev1.ResourceEphemeralStorage || isExtendedResource(name)
+}
+
+// podRequests returns effective requests of the pod the way the scheduler computes them: a sum
+// of container requests or the largest init container request if it's bigger, for each resource.
+// TODO: add PodSpec.Overhead of the RuntimeClass, it isn't available in the vendored k8s.io/api.
+func podRequests(pod *corev1.Pod) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for _, c := range pod.Spec.Containers {
+		addScalar(out, c.Resources.Requests)
+	}
+	// init containers run one by one before the regular ones
+	for _, c := range pod.Spec.InitContainers {
+		for name, q := range c.Resources.Requests {
+			if cur, ok := out[name]; !ok || q.Cmp(cur) > 0 {
+				out[name] = q.DeepCopy()
+			}
+		}
+	}
+	return out
+}
+
+// getScalarForScheduling returns ephemeral storage and extended resources requested by the pod
+// along with a pod slot on a node.
+func getScalarForScheduling(pod *corev1.Pod) corev1.ResourceList {
+	out := corev1.ResourceList{
+		corev1.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	for name, q := range podRequests(pod) {
+		if isScalarResource(name) {
+			out[name] = q
+		}
+	}
+	return out
+}
+
+// hasExtendedRequests reports whether any of the pods requests extended resources.
+func hasExtendedRequests(pods []*corev1.Pod) bool {
+	for _, pod := range pods {
+		for name, q := range getScalarForScheduling(pod) {
+			if isExtendedResource(name) && !q.IsZero() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func totalScalar(pods []*corev1.Pod) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	for _, pod := range pods {
+		addScalar(out, getScalarForScheduling(pod))
+	}
+	return out
+}
+
+func addScalar(to, list corev1.ResourceList) {
+	for name, q := range list {
+		total := to[name]
+		total.Add(q)
+		to[name] = total
+	}
+}
+
+// machineScalar returns ephemeral storage, pod slots and extended resources of a new machine of
+// the type. Storage and pod slots are omitted if they aren't known.
+func machineScalar(m *provider.MachineType) corev1.ResourceList {
+	out := corev1.ResourceList{}
+	if m.MaxPods > 0 {
+		out[corev1.ResourcePods] = *resource.NewQuantity(int64(m.MaxPods), resource.DecimalSI)
+	}
+	if !m.StorageResource.IsZero() {
+		out[corev1.ResourceEphemeralStorage] = m.StorageResource.DeepCopy()
+	}
+	for name, q := range m.Extended() {
+		out[corev1.ResourceName(name)] = q.DeepCopy()
+	}
+	return out
+}
+
+// fitsScalar reports whether the free resources satisfy the requested ones. Unknown ephemeral
+// storage and pod slots are assumed to be enough.
+func fitsScalar(free, requests corev1.ResourceList) bool {
+	for name, q := range requests {
+		if q.IsZero() {
+			continue
+		}
+		f, ok := free[name]
+		if !ok && (name == corev1.ResourceEphemeralStorage || name == corev1.ResourcePods) {
+			continue
+		}
+		if !ok || f.Cmp(q) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func subScalar(free, requests corev1.ResourceList) {
+	for name, q := range requests {
+		if f, ok := free[name]; ok {
+			f.Sub(q)
+			free[name] = f
+		}
+	}
+}
+
+func hasScalar(m *provider.MachineType, requests corev1.ResourceList) bool {
+	return fitsScalar(machineScalar(m), requests)
+}
+
+// preferredMachines returns machine types without GPUs or other extended resources if none of
+// the pods requests them and some of those types are able to run a pod, so expensive GPU machines
+// aren't created for regular pods. All the machine types are returned otherwise.
+func preferredMachines(pods []*corev1.Pod, machineTypes []*provider.MachineType) []*provider.MachineType {
+	if hasExtendedRequests(pods) {
+		return machineTypes
+	}
+
+	regular := make([]*provider.MachineType, 0, len(machineTypes))
+	for _, m := range machineTypes {
+		if !m.HasExtended() {
+			regular = append(regular, m)
+		}
+	}
+	for _, pod := range pods {
+		if hasMachineFor(regular, pod) {
+			return regular
+		}
+	}
+	return machineTypes
+}
@@ -0,0 +1,164 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+var (
+	vmP2xLarge = provider.MachineType{
+		Name:           "p2.xlarge",
+		CPUResource:    resource4,
+		MemoryResource: resource.MustParse("61Gi"),
+		PriceHour:      0.9,
+		GPU:            1,
+	}
+	vmP28xLarge = provider.MachineType{
+		Name:           "p2.8xlarge",
+		CPUResource:    resource.MustParse("32"),
+		MemoryResource: resource.MustParse("488Gi"),
+		PriceHour:      7.2,
+		GPU:            8,
+	}
+)
+
+func testExtendedPod(cpu, mem string, ext corev1.ResourceList) *corev1.Pod {
+	pod := testPod("", cpu, mem)
+	for name, q := range ext {
+		pod.Spec.Containers[0].Resources.Requests[name] = q
+	}
+	return pod
+}
+
+func TestPodRequests(t *testing.T) {
+	pod := testPod("", "1", "1Gi")
+	pod.Spec.Containers = append(pod.Spec.Containers, testPod("", "1", "1Gi").Spec.Containers...)
+	pod.Spec.InitContainers = append(testPod("", "500m", "4Gi").Spec.Containers, testPod("", "1", "1Gi").Spec.Containers...)
+	pod.Spec.InitContainers[0].Resources.Requests[corev1.ResourceEphemeralStorage] = resource.MustParse("10Gi")
+
+	requests := podRequests(pod)
+	cpu, mem := getCPUMemForScheduling(pod)
+	storage := requests[corev1.ResourceEphemeralStorage]
+	require.Equal(t, int64(2000), cpu.MilliValue(), "cpu is a sum of containers requests")
+	require.Equal(t, int64(4<<30), mem.Value(), "memory is the largest init container request")
+	require.Equal(t, int64(10<<30), storage.Value(), "storage is requested by an init container only")
+}
+
+func TestGetScalarForScheduling(t *testing.T) {
+	pod := testExtendedPod("1", "1Gi", corev1.ResourceList{
+		provider.ResourceGPU:             resource1,
+		"example.com/fpga":               resource2,
+		corev1.ResourceEphemeralStorage:  resource1,
+		"kubernetes.io/custom":           resource1,
+		corev1.ResourceName("hugepages"): resource1,
+	})
+	pod.Spec.Containers = append(pod.Spec.Containers, pod.Spec.Containers[0])
+
+	scalar := getScalarForScheduling(pod)
+	require.Len(t, scalar, 4)
+	gpu, fpga, storage := scalar[provider.ResourceGPU], scalar["example.com/fpga"], scalar[corev1.ResourceEphemeralStorage]
+	require.Equal(t, int64(2), gpu.Value())
+	require.Equal(t, int64(4), fpga.Value())
+	require.Equal(t, int64(2), storage.Value())
+	pods := scalar[corev1.ResourcePods]
+	require.Equal(t, int64(1), pods.Value())
+}
+
+func TestStorageMachineSelection(t *testing.T) {
+	small, large := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G
+	small.StorageResource = resource.MustParse("20Gi")
+	large.StorageResource = resource.MustParse("100Gi")
+	unknown := vmM42xLargePrice02CPU2Mem4G
+
+	pod := testExtendedPod("1", "1Gi", corev1.ResourceList{corev1.ResourceEphemeralStorage: resource.MustParse("50Gi")})
+	require.False(t, hasMachineFor([]*provider.MachineType{&small}, pod))
+	require.True(t, hasMachineFor([]*provider.MachineType{&unknown}, pod))
+
+	mtype, err := machineToScale([]*corev1.Pod{pod}, []*provider.MachineType{&small, &large}, api.SmallCPUBox, nil)
+	require.Nil(t, err)
+	require.Equal(t, large.Name, mtype.Name)
+}
+
+func TestExtendedMachineSelection(t *testing.T) {
+	pod1GPU := testExtendedPod("2", "8Gi", corev1.ResourceList{provider.ResourceGPU: resource1})
+	pod4GPU := testExtendedPod("2", "8Gi", corev1.ResourceList{provider.ResourceGPU: resource4})
+	podFPGA := testExtendedPod("1", "1Gi", corev1.ResourceList{"example.com/fpga": resource1})
+	pod1CPU := testPod("", "1", "4Gi")
+	pod16CPU := testPod("", "16", "128Gi")
+
+	tcs := []struct {
+		pods         []*corev1.Pod
+		hasMachine   bool
+		expectedVM   provider.MachineType
+		expectedPref int
+	}{
+		{
+			pods:         []*corev1.Pod{pod1CPU},
+			hasMachine:   true,
+			expectedVM:   vmM4LargePrice02CPU2Mem4G,
+			expectedPref: 3,
+		},
+		{
+			// gpu types are used if nothing else fits
+			pods:         []*corev1.Pod{pod16CPU},
+			hasMachine:   true,
+			expectedVM:   vmP28xLarge,
+			expectedPref: 5,
+		},
+		{
+			pods:         []*corev1.Pod{pod1GPU},
+			hasMachine:   true,
+			expectedVM:   vmP2xLarge,
+			expectedPref: 5,
+		},
+		{
+			pods:         []*corev1.Pod{pod4GPU, pod1CPU},
+			hasMachine:   true,
+			expectedVM:   vmP28xLarge,
+			expectedPref: 5,
+		},
+		{
+			// no allowed machine type provides the resource
+			pods:         []*corev1.Pod{podFPGA},
+			expectedPref: 5,
+		},
+	}
+
+	vmTypes := []*provider.MachineType{&vmM4LargePrice02CPU2Mem4G, &vmM4xLargePrice02CPU2Mem4G, &vmM42xLargePrice02CPU2Mem4G,
+		&vmP2xLarge, &vmP28xLarge}
+	for i, tc := range tcs {
+		require.Equalf(t, tc.hasMachine, hasMachineFor(vmTypes, tc.pods[0]), "TC#%d: check has machine", i+1)
+
+		pref := preferredMachines(tc.pods, vmTypes)
+		require.Lenf(t, pref, tc.expectedPref, "TC#%d: check preferred machines", i+1)
+		if !tc.hasMachine {
+			continue
+		}
+
+		mtype, err := machineToScale(tc.pods, vmTypes, api.BigBox, nil)
+		require.Nilf(t, err, "TC#%d: check error", i+1)
+		require.Equalf(t, tc.expectedVM, mtype, "TC#%d: check machine type", i+1)
+	}
+}
+
+func TestMaxPodsMachineSelection(t *testing.T) {
+	small, large := vmM4LargePrice02CPU2Mem4G, vmM4xLargePrice02CPU2Mem4G
+	small.MaxPods, large.MaxPods = 4, 20
+	pod := testPod("", "100m", "100Mi")
+	pods := []*corev1.Pod{pod, pod, pod, pod, pod, pod}
+
+	require.Equal(t, 4, pack(&small, pods).pods)
+	require.Equal(t, 6, pack(&large, pods).pods)
+
+	for _, strategy := range []api.ScaleUpStrategy{api.BigBox, api.MostPods} {
+		mtype, err := machineToScale(pods, []*provider.MachineType{&small, &large}, strategy, nil)
+		require.Nilf(t, err, "%s: check error", strategy)
+		require.Equalf(t, large.Name, mtype.Name, "%s: check machine type", strategy)
+	}
+}
@@ -14,7 +14,9 @@ import (
 )
 
 // TODO: use workers here
-func (s *Kubescaler) scaleDown(scheduledPods []*corev1.Pod, workerList *api.WorkerList, ignoreLabels map[string]string, lifespanMin int, currentTime time.Time) error {
+// scaleDown removes empty workers, limit keeps the cluster from going below the WorkersCountMin.
+// Workers are kept if the optional keep function returns true, e.g. to provide the headroom.
+func (s *Kubescaler) scaleDown(scheduledPods []*corev1.Pod, workerList *api.WorkerList, limit int, keep func(w *api.Worker) bool, ignoreLabels map[string]string, lifespanMin int, currentTime time.Time) error {
 	// TODO: don't skip failed stateful pods?
 	scheduledPods = filterOutDaemonSetPods(filterOutStandalonePods(scheduledPods))
 	nodePodsMap := nodePodsMap(scheduledPods)
@@ -38,10 +40,18 @@ func (s *Kubescaler) scaleDown(scheduledPods []*corev1.Pod, workerList *api.Work
 	}()
 
 	for _, w := range emptyapi {
+		if len(removed) >= limit {
+			ignored = append(ignored, fmt.Sprintf("%s(%s,workersCountMin)", w.NodeName, w.MachineID))
+			continue
+		}
 		if reason := ignoreReason(w, ignoreLabels, lifespanMin, currentTime); reason != "" {
 			ignored = append(ignored, fmt.Sprintf("%s(%s,%s)", w.NodeName, w.MachineID, reason))
 			continue
 		}
+		if keep != nil && keep(w) {
+			ignored = append(ignored, fmt.Sprintf("%s(%s,headroom)", w.NodeName, w.MachineID))
+			continue
+		}
 
 		if _, err := s.DeleteWorker(context.Background(), w.NodeName, w.MachineID); err != nil {
 			return err
@@ -1,6 +1,7 @@
 package kubescaler
 
 import (
+	"context"
 	"os"
 	"sync"
 	"testing"
@@ -81,12 +82,69 @@ func TestKubescalerScaleDown(t *testing.T) {
 			workerManager: fake.NewManager(tc.providerErr),
 		}
 
-		err = ks.scaleDown(tc.pods, tc.workerList, nil, 0, time.Now())
+		err = ks.scaleDown(tc.pods, tc.workerList, len(tc.workerList.Items), nil, nil, 0, time.Now())
 		require.Equalf(t, tc.expectedErr, err, "TC#%d", i+1)
 	}
 
 }
 
+// deletingManager records deleted workers.
+type deletingManager struct {
+	*fake.Manager
+	deleted []string
+}
+
+func (m *deletingManager) DeleteWorker(ctx context.Context, nodeName, id string) (*api.Worker, error) {
+	m.deleted = append(m.deleted, id)
+	return m.Manager.DeleteWorker(ctx, nodeName, id)
+}
+
+func TestScaleDownLimit(t *testing.T) {
+	workerList := &api.WorkerList{
+		Items: []*api.Worker{
+			{NodeName: "node-1", MachineID: "i-1"},
+			{NodeName: "node-2", MachineID: "i-2"},
+			{NodeName: "node-3", MachineID: "i-3"},
+		},
+	}
+
+	tcs := []struct {
+		limit           int
+		keep            func(w *api.Worker) bool
+		expectedDeleted []string
+	}{
+		{
+			limit:           3,
+			expectedDeleted: []string{"i-1", "i-2", "i-3"},
+		},
+		{
+			limit:           1,
+			expectedDeleted: []string{"i-1"},
+		},
+		{
+			limit: 0,
+		},
+		{
+			limit:           3,
+			keep:            func(w *api.Worker) bool { return w.NodeName == "node-2" },
+			expectedDeleted: []string{"i-1", "i-3"},
+		},
+		{
+			limit:           1,
+			keep:            func(w *api.Worker) bool { return w.NodeName != "node-3" },
+			expectedDeleted: []string{"i-3"},
+		},
+	}
+
+	for i, tc := range tcs {
+		m := &deletingManager{Manager: fake.NewManager(nil)}
+		ks := &Kubescaler{workerManager: m}
+
+		require.Nilf(t, ks.scaleDown(nil, workerList, tc.limit, tc.keep, nil, 0, time.Now()), "TC#%d", i+1)
+		require.Equalf(t, tc.expectedDeleted, m.deleted, "TC#%d", i+1)
+	}
+}
+
 func TestPodsPerNode(t *testing.T) {
 	pods := []*corev1.Pod{&podStandAlone, &podWithRequests}
 	require.Equal(t, map[string][]string{"": {podStandAlone.Name}, NodeReadyName: {podWithRequests.Name}}, nodePodsMap(pods))
@@ -10,6 +10,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/supergiant/capacity/pkg/api"
 	"github.com/supergiant/capacity/pkg/log"
@@ -18,58 +20,254 @@ import (
 
 var ErrNoResourcesRequested = errors.New("empty cpu and RAM value")
 
-func (s *Kubescaler) scaleUp(unscheduledPods []*corev1.Pod, machineTypes []*provider.MachineType, strategy api.ScaleUpStrategy, currentTime time.Time) (bool, error) {
-	podsToScale, podsIgnored := filterPods(unscheduledPods, machineTypes, currentTime)
+// scaleUp creates a worker for unscheduled pods, headroom pods are virtual ones that
+// don't fit on ready nodes.
+func (s *Kubescaler) scaleUp(unscheduledPods, headroom []*corev1.Pod, filter podFilter, machineTypes []*provider.MachineType, strategy api.ScaleUpStrategy, priorities []string, currentTime time.Time) (bool, error) {
+	podsToScale, podsIgnored := filterPods(unscheduledPods, filter, machineTypes, currentTime)
 	if len(podsIgnored) > 0 {
 		log.Debugf("ignored pods to scale: %v", podsIgnored)
 	}
+	podsToScale = append(podsToScale, headroom...)
 	if len(podsToScale) == 0 {
 		return false, nil
 	}
+	zone, podsToScale := filter.zoneToScale(podsToScale)
 
 	log.Debugf("kubescaler: run: scale up: unscheduled pods: %v", podNames(podsToScale))
 
-	mtype, err := machineToScale(unscheduledPods, machineTypes, strategy)
-	if err != nil {
-		return false, errors.Wrap(err, "find an appropriate machine type")
+	// machine types that have failed recently are skipped, the next best one is used instead
+	candidates := s.backoff.available(machineTypes, currentTime)
+	if len(candidates) == 0 {
+		log.Infof("kubescaler: run: scale up: all allowed machine types are backed off")
+		return false, nil
 	}
 
-	worker, err := s.CreateWorker(context.Background(), mtype.Name)
-	if err != nil {
-		return true, errors.Wrap(err, "create a worker")
+	var lastErr error
+	for attempt := 0; attempt < maxCreateAttempts && len(candidates) > 0; attempt++ {
+		// ignored pods shouldn't affect a machine type either
+		mtype, err := machineToScale(podsToScale, candidates, strategy, priorities)
+		if err != nil {
+			if lastErr != nil {
+				break
+			}
+			return false, errors.Wrap(err, "find an appropriate machine type")
+		}
+
+		worker, err := s.createWorker(mtype.Name, zone)
+		if err != nil {
+			if !provider.IsMachineTypeError(err) {
+				return false, errors.Wrap(err, "create a worker")
+			}
+			d := s.backoff.failed(mtype.Name, err, currentTime)
+			log.Errorf("kubescaler: run: scale up: create a %s worker: %v: back off the type for %s", mtype.Name, err, d)
+			candidates, lastErr = withoutMachine(candidates, mtype.Name), err
+			continue
+		}
+		s.backoff.succeeded(mtype.Name)
+
+		if zone != "" {
+			log.Infof("kubescaler: run: scale up: has created a %s worker (%s) in the %s zone", worker.MachineType, worker.MachineID, zone)
+		} else {
+			log.Infof("kubescaler: run: scale up: has created a %s worker (%s)", worker.MachineType, worker.MachineID)
+		}
+		return true, nil
 	}
+	return false, errors.Wrap(lastErr, "create a worker")
+}
 
-	log.Infof("kubescaler: run: scale up: has created a %s worker (%s)", worker.MachineType, worker.MachineID)
-	return true, err
+// createWorker creates a worker of the machine type, the zone is chosen by the provider if it's empty.
+func (s *Kubescaler) createWorker(mtype, zone string) (*api.Worker, error) {
+	if zone != "" {
+		return s.CreateWorkerInZone(context.Background(), mtype, zone)
+	}
+	return s.CreateWorker(context.Background(), mtype)
 }
 
-func machineToScale(pods []*corev1.Pod, machineTypes []*provider.MachineType, strategy api.ScaleUpStrategy) (provider.MachineType, error) {
-	// get required cpu/mem for unscheduled pods and pick up a machine type
-	var cpu, mem resource.Quantity
-	switch strategy {
-	case api.SmallCPUBox:
-		cpu, mem = smallestCPUMem(pods)
-	case api.SmallMemBox:
-		cpu, mem = smallestMemCPU(pods)
-	default:
-		cpu, mem = totalCPUMem(pods)
+// scaleToMin creates the missing workers of the default machine type, the cheapest
+// allowed one is used if it isn't set. Backed off machine types are skipped, the next
+// one is tried if the provider fails to create a worker of the type.
+func (s *Kubescaler) scaleToMin(missing int, defaultType string, machineTypes []*provider.MachineType, currentTime time.Time) error {
+	if len(machineTypes) == 0 {
+		return ErrNoAllowedMachines
+	}
+	candidates := s.backoff.available(machineTypes, currentTime)
+	if len(candidates) == 0 {
+		log.Infof("kubescaler: run: scale up: all allowed machine types are backed off")
+		return nil
+	}
+
+	for created, failures := 0, 0; created < missing; {
+		mtype := provider.SortedMachineTypes(candidates)[0]
+		if m := findMachine(defaultType, candidates); m != nil {
+			mtype = m
+		}
+
+		worker, err := s.CreateWorker(context.Background(), mtype.Name)
+		if err != nil {
+			if !provider.IsMachineTypeError(err) {
+				return errors.Wrap(err, "create a worker")
+			}
+			d := s.backoff.failed(mtype.Name, err, currentTime)
+			log.Errorf("kubescaler: run: scale up: create a %s worker: %v: back off the type for %s", mtype.Name, err, d)
+			failures++
+			if candidates = withoutMachine(candidates, mtype.Name); len(candidates) == 0 || failures == maxCreateAttempts {
+				return errors.Wrap(err, "create a worker")
+			}
+			// try the next machine type for the same worker
+			continue
+		}
+		s.backoff.succeeded(mtype.Name)
+		created++
+		log.Infof("kubescaler: run: scale up: has created a %s worker (%s) to meet workersCountMin",
+			worker.MachineType, worker.MachineID)
 	}
+	return nil
+}
 
-	mtype, err := bestMachineFor(cpu, mem, machineTypes)
+// machineToScale picks a machine type for the pods with the strategy expander, priorities are
+// machine type patterns for the priority strategy.
+func machineToScale(pods []*corev1.Pod, machineTypes []*provider.MachineType, strategy api.ScaleUpStrategy, priorities []string) (provider.MachineType, error) {
+	mtype, err := newExpander(strategy, priorities).machineFor(pods, preferredMachines(pods, machineTypes))
 	if err != nil {
 		return provider.MachineType{}, errors.Wrap(err, "find an appropriate machine type")
 	}
 
+	cpu, mem := totalCPUMem(pods)
 	log.Debugf("kubescaler: run: scale up: strategy %s: unscheduled pod(s) needs cpu=%s, mem=%s: pick the %s machine (cpu=%s, mem=%s)",
 		strategy, cpu.String(), mem.String(), mtype.Name, mtype.CPU, mtype.Memory)
 	return mtype, nil
 }
 
-func filterPods(pods []*corev1.Pod, allowedMachines []*provider.MachineType, currentTime time.Time) ([]*corev1.Pod, []string) {
+// podFilter holds config rules for pods that shouldn't trigger scale up.
+type podFilter struct {
+	priorityCutoff *int32
+	namespaces     map[string]bool
+	selectors      []labels.Selector
+	// zones workers could be created in, nil if the provider doesn't support them
+	zones map[string]bool
+	// volumeZones are zones of pods with zonal volumes
+	volumeZones map[types.UID][]string
+}
+
+func newPodFilter(cfg api.Config) podFilter {
+	f := podFilter{
+		priorityCutoff: cfg.ScaleUpPriorityCutoff,
+		namespaces:     make(map[string]bool, len(cfg.IgnoredPodNamespaces)),
+		selectors:      make([]labels.Selector, 0, len(cfg.IgnoredPodSelectors)),
+	}
+	for _, ns := range cfg.IgnoredPodNamespaces {
+		f.namespaces[ns] = true
+	}
+	for _, s := range cfg.IgnoredPodSelectors {
+		// selectors are checked on validation, an empty one would match all pods
+		if sel, err := labels.Parse(s); err == nil && !sel.Empty() {
+			f.selectors = append(f.selectors, sel)
+		}
+	}
+	return f
+}
+
+// withZones returns the filter with zones workers could be created in and zones of pods volumes.
+func (f podFilter) withZones(zones []string, volumeZones map[types.UID][]string) podFilter {
+	f.zones = make(map[string]bool, len(zones))
+	for _, z := range zones {
+		f.zones[z] = true
+	}
+	f.volumeZones = volumeZones
+	return f
+}
+
+// hasUnreachableZone reports whether the pod volumes are in zones workers can't be created in.
+func (f podFilter) hasUnreachableZone(pod *corev1.Pod) bool {
+	podZones, ok := f.volumeZones[pod.UID]
+	if !ok || f.zones == nil {
+		return false
+	}
+	for _, z := range podZones {
+		if f.zones[z] {
+			return false
+		}
+	}
+	return true
+}
+
+// zoneToScale picks a zone for a new worker: the first one required by volumes of the pods.
+// Pods that can't run in the zone are left for next scans. An empty zone is returned if none
+// of the pods requires one.
+func (f podFilter) zoneToScale(pods []*corev1.Pod) (string, []*corev1.Pod) {
+	if f.zones == nil {
+		return "", pods
+	}
+
+	zone := ""
+	for _, pod := range pods {
+		for _, z := range f.volumeZones[pod.UID] {
+			if f.zones[z] {
+				zone = z
+				break
+			}
+		}
+		if zone != "" {
+			break
+		}
+	}
+	if zone == "" {
+		return "", pods
+	}
+
+	inZone := make([]*corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		podZones, ok := f.volumeZones[pod.UID]
+		if !ok || len(intersectZones(podZones, []string{zone})) > 0 {
+			inZone = append(inZone, pod)
+		}
+	}
+	return zone, inZone
+}
+
+func (f podFilter) hasIgnoredNamespace(pod *corev1.Pod) bool {
+	return f.namespaces[pod.Namespace]
+}
+
+func (f podFilter) hasIgnoredLabels(pod *corev1.Pod) bool {
+	for _, sel := range f.selectors {
+		if sel.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f podFilter) isLowPriority(pod *corev1.Pod) bool {
+	if f.priorityCutoff == nil {
+		return false
+	}
+	// pods without a priority class have a zero priority
+	var priority int32
+	if pod.Spec.Priority != nil {
+		priority = *pod.Spec.Priority
+	}
+	return priority < *f.priorityCutoff
+}
+
+func (f podFilter) filterOutLowPriority(pods []*corev1.Pod) []*corev1.Pod {
+	if f.priorityCutoff == nil {
+		return pods
+	}
+	filtered := make([]*corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if !f.isLowPriority(pod) {
+			filtered = append(filtered, pod)
+		}
+	}
+	return filtered
+}
+
+func filterPods(pods []*corev1.Pod, filter podFilter, allowedMachines []*provider.MachineType, currentTime time.Time) ([]*corev1.Pod, []string) {
 	toScale := make([]*corev1.Pod, 0)
 	ignored := make([]string, 0)
 	for _, pod := range pods {
-		ignore, reason := isIgnored(pod, allowedMachines, currentTime)
+		ignore, reason := isIgnored(pod, filter, allowedMachines, currentTime)
 		if ignore {
 			ignored = append(ignored, fmt.Sprintf("%s/%s=%s", pod.Namespace, pod.Name, reason))
 			continue
@@ -79,10 +277,20 @@ func filterPods(pods []*corev1.Pod, allowedMachines []*provider.MachineType, cur
 	return toScale, ignored
 }
 
-func isIgnored(pod *corev1.Pod, allowedMachines []*provider.MachineType, currentTime time.Time) (bool, string) {
+func isIgnored(pod *corev1.Pod, filter podFilter, allowedMachines []*provider.MachineType, currentTime time.Time) (bool, string) {
 	switch {
 	case isNewPod(pod, currentTime):
 		return true, "new-pod"
+	case filter.isLowPriority(pod):
+		// skip pods that are expected to wait
+		return true, "low-priority"
+	case filter.hasIgnoredNamespace(pod):
+		return true, "ignored-namespace"
+	case filter.hasIgnoredLabels(pod):
+		return true, "ignored-labels"
+	case filter.hasUnreachableZone(pod):
+		// skip pods with volumes in zones without configured subnets
+		return true, "volume-zone-unreachable"
 	case !hasController(pod):
 		// skip standalone pods
 		return true, "standalone-pod"
@@ -101,15 +309,18 @@ func isIgnored(pod *corev1.Pod, allowedMachines []*provider.MachineType, current
 
 func hasMachineFor(machineTypes []*provider.MachineType, pod *corev1.Pod) bool {
 	cpu, mem := getCPUMemForScheduling(pod)
+	ext := getScalarForScheduling(pod)
 	for _, m := range machineTypes {
-		if hasResources(m, cpu, mem) {
+		if hasResources(m, cpu, mem) && hasScalar(m, ext) {
 			return true
 		}
 	}
 	return false
 }
 
-func bestMachineFor(cpu, mem resource.Quantity, machineTypes []*provider.MachineType) (provider.MachineType, error) {
+// bestMachineFor returns the cheapest machine type with the requested resources. The biggest one
+// with the requested extended resources (or of all types if none has them) is returned if nothing fits.
+func bestMachineFor(cpu, mem resource.Quantity, ext corev1.ResourceList, machineTypes []*provider.MachineType) (provider.MachineType, error) {
 	if cpu.Value() == 0 && mem.Value() == 0 {
 		return provider.MachineType{}, ErrNoResourcesRequested
 	}
@@ -117,13 +328,20 @@ func bestMachineFor(cpu, mem resource.Quantity, machineTypes []*provider.Machine
 		return provider.MachineType{}, ErrNoAllowedMachines
 	}
 
-	var biggest *provider.MachineType
+	var biggest, biggestExt *provider.MachineType
 	// machine types are sorted by price
 	for _, m := range provider.SortedMachineTypes(machineTypes) {
+		if !hasScalar(m, ext) {
+			biggest = takeBest(biggest, m)
+			continue
+		}
 		if hasResources(m, cpu, mem) {
 			return *m, nil
 		}
-		biggest = takeBest(biggest, m)
+		biggestExt = takeBest(biggestExt, m)
+	}
+	if biggestExt != nil {
+		return *biggestExt, nil
 	}
 	return *biggest, nil
 }
@@ -149,12 +367,8 @@ func hasDaemonSetController(pod *corev1.Pod) bool {
 func getCPUMemForScheduling(pod *corev1.Pod) (resource.Quantity, resource.Quantity) {
 	// Scheduling is based on requests.
 	// https://github.com/kubernetes/community/blob/master/contributors/design-proposals/node/resource-qos.md#requests-and-limits
-	var cpu, mem resource.Quantity
-	for _, c := range pod.Spec.Containers {
-		cpu.Add(*c.Resources.Requests.Cpu())
-		mem.Add(*c.Resources.Requests.Memory())
-	}
-	return cpu, mem
+	requests := podRequests(pod)
+	return requests[corev1.ResourceCPU], requests[corev1.ResourceMemory]
 }
 
 func totalCPUMem(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
@@ -167,9 +381,10 @@ func totalCPUMem(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
 	return cpu, mem
 }
 
-func smallestCPUMem(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
+// smallestByCPU returns the pod with the smallest cpu requests, the memory ones are compared on a tie.
+func smallestByCPU(pods []*corev1.Pod) *corev1.Pod {
 	if len(pods) == 0 {
-		return resource.Quantity{}, resource.Quantity{}
+		return &corev1.Pod{}
 	}
 	sort.Slice(pods, func(i, j int) bool {
 		icpu, imem := getCPUMemForScheduling(pods[i])
@@ -182,12 +397,13 @@ func smallestCPUMem(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
 		}
 		return lessCPU
 	})
-	return getCPUMemForScheduling(pods[0])
+	return pods[0]
 }
 
-func smallestMemCPU(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
+// smallestByMem returns the pod with the smallest memory requests, the cpu ones are compared on a tie.
+func smallestByMem(pods []*corev1.Pod) *corev1.Pod {
 	if len(pods) == 0 {
-		return resource.Quantity{}, resource.Quantity{}
+		return &corev1.Pod{}
 	}
 	sort.Slice(pods, func(i, j int) bool {
 		icpu, imem := getCPUMemForScheduling(pods[i])
@@ -200,7 +416,7 @@ func smallestMemCPU(pods []*corev1.Pod) (resource.Quantity, resource.Quantity) {
 		}
 		return lessMem
 	})
-	return getCPUMemForScheduling(pods[0])
+	return pods[0]
 }
 
 func podNames(pods []*corev1.Pod) []string {
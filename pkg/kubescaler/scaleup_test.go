@@ -505,12 +505,38 @@ func TestKubescalerScaleUp(t *testing.T) {
 			workerManager: fake.NewManager(tc.providerErr),
 		}
 
-		_, err = ks.scaleUp(tc.pods, allowedMachines, "", currentTime)
+		_, err = ks.scaleUp(tc.pods, nil, podFilter{}, allowedMachines, "", nil, currentTime)
 		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC#%d", i+1)
 	}
 
 }
 
+func TestCountWorkers(t *testing.T) {
+	workerList := &api.WorkerList{
+		Items: []*api.Worker{
+			{MachineState: "pending"},
+			{MachineState: "running", NodeState: "ready"},
+			{MachineState: "running"},
+			{MachineState: "shutting-down", NodeState: "ready"},
+		},
+	}
+
+	count, ready := countWorkers(workerList)
+	require.Equal(t, 3, count)
+	require.Equal(t, 1, ready)
+}
+
+func TestScaleToMin(t *testing.T) {
+	ks := &Kubescaler{
+		workerManager: fake.NewManager(nil),
+	}
+	require.Nil(t, ks.scaleToMin(2, "unknown", []*provider.MachineType{&allowedMachine}, currentTime))
+	require.Equal(t, ErrNoAllowedMachines, ks.scaleToMin(2, "", nil, currentTime))
+
+	ks.workerManager = fake.NewManager(errFake)
+	require.Equal(t, errFake, errors.Cause(ks.scaleToMin(1, allowedMachine.Name, []*provider.MachineType{&allowedMachine}, currentTime)))
+}
+
 func TestMachineToScale_SmallCPUBox(t *testing.T) {
 	tcs := []struct {
 		name        string
@@ -588,7 +614,7 @@ func TestMachineToScale_SmallCPUBox(t *testing.T) {
 	vmTypes := []*provider.MachineType{&vmM4LargePrice02CPU2Mem4G, &vmM4xLargePrice02CPU2Mem4G, &vmM42xLargePrice02CPU2Mem4G,
 		&vmR5LargePrice02CPU2Mem4G, &vmR5xLargePrice02CPU2Mem4G, &vmR52xLargePrice02CPU2Mem4G}
 	for _, tc := range tcs {
-		mtype, err := machineToScale(tc.pods, vmTypes, api.SmallCPUBox)
+		mtype, err := machineToScale(tc.pods, vmTypes, api.SmallCPUBox, nil)
 		require.Equalf(t, tc.expectedErr, errors.Cause(err), "TC: %s: check error", tc.name)
 		require.Equalf(t, tc.expectedVM, mtype, "TC: %s: check machine type", tc.name)
 	}
@@ -607,10 +633,48 @@ func TestFilterPods(t *testing.T) {
 	allowedMachines := []*provider.MachineType{&allowedMachine}
 	expectedRes := []*corev1.Pod{&podWithRequests}
 
-	toScale, _ := filterPods(pods, allowedMachines, currentTime)
+	toScale, _ := filterPods(pods, podFilter{}, allowedMachines, currentTime)
 	require.Equal(t, expectedRes, toScale)
 }
 
+func TestFilterLowPriorityPods(t *testing.T) {
+	low, high := int32(-10), int32(1000)
+	podLow, podHigh := podWithRequests, podWithRequests
+	podLow.Spec.Priority = &low
+	podHigh.Spec.Priority = &high
+
+	cutoff := int32(0)
+	filter := podFilter{priorityCutoff: &cutoff}
+	allowedMachines := []*provider.MachineType{&allowedMachine}
+
+	// pods without a priority have a zero one
+	toScale, ignored := filterPods([]*corev1.Pod{&podLow, &podHigh, &podWithRequests}, filter, allowedMachines, currentTime)
+	require.Equal(t, []*corev1.Pod{&podHigh, &podWithRequests}, toScale)
+	require.Equal(t, []string{podLow.Namespace + "/" + podLow.Name + "=low-priority"}, ignored)
+
+	require.Equal(t, []*corev1.Pod{&podHigh, &podWithRequests}, filter.filterOutLowPriority([]*corev1.Pod{&podLow, &podHigh, &podWithRequests}))
+	require.Len(t, podFilter{}.filterOutLowPriority([]*corev1.Pod{&podLow, &podHigh}), 2)
+}
+
+func TestFilterIgnoredPods(t *testing.T) {
+	podCI, podExperiment := podWithRequests, podWithRequests
+	podCI.Namespace = "ci"
+	podExperiment.Labels = map[string]string{"experiment": "b"}
+
+	filter := newPodFilter(api.Config{
+		IgnoredPodNamespaces: []string{"ci"},
+		IgnoredPodSelectors:  []string{"app=ci", "experiment in (a,b)", ""},
+	})
+	allowedMachines := []*provider.MachineType{&allowedMachine}
+
+	toScale, ignored := filterPods([]*corev1.Pod{&podCI, &podExperiment, &podWithRequests}, filter, allowedMachines, currentTime)
+	require.Equal(t, []*corev1.Pod{&podWithRequests}, toScale)
+	require.Equal(t, []string{
+		"ci/" + podCI.Name + "=ignored-namespace",
+		podExperiment.Namespace + "/" + podExperiment.Name + "=ignored-labels",
+	}, ignored)
+}
+
 func TestHasMachineFor(t *testing.T) {
 	tcs := []struct {
 		pod          *corev1.Pod
@@ -698,7 +762,7 @@ func TestBestMachineFor(t *testing.T) {
 	}
 
 	for i, tc := range tcs {
-		res, err := bestMachineFor(tc.cpu, tc.mem, tc.machineTypes)
+		res, err := bestMachineFor(tc.cpu, tc.mem, nil, tc.machineTypes)
 		require.Equalf(t, tc.expectedErr, err, "TC#%d", i+1)
 		if err == nil {
 			require.Equalf(t, tc.expectedRes, res, "TC#%d", i+1)
@@ -0,0 +1,202 @@
+package kubescaler
+
+import (
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider/factory"
+)
+
+// Reference kinds:
+const (
+	refEnv    = "env"
+	refSecret = "secret"
+)
+
+var (
+	// refRegexp matches values like '${env:AWS_SECRET}' or '${secret:kube-system/aws/secretKey}'.
+	refRegexp = regexp.MustCompile(`^\$\{(env|secret):([^}]+)\}$`)
+
+	ErrInvalidRef = errors.New("invalid reference")
+)
+
+// secretResolver resolves references to environment variables and kubernetes Secrets
+// in the config values. Only references are stored in the config, so credentials
+// aren't kept in the ConfigMap/file in plaintext.
+type secretResolver struct {
+	client           v1.SecretsGetter
+	defaultNamespace string
+}
+
+func newSecretResolver(client v1.SecretsGetter, defaultNamespace string) *secretResolver {
+	if defaultNamespace == "" {
+		defaultNamespace = api.DefaultConfigMapNamespace
+	}
+	return &secretResolver{
+		client:           client,
+		defaultNamespace: defaultNamespace,
+	}
+}
+
+// resolve returns a copy of the config with resolved references and provider parameters
+// overridden by environment variables. It also returns resource versions of the referenced
+// Secrets, they are used to detect changes.
+func (r *secretResolver) resolve(conf api.Config) (api.Config, map[string]string, error) {
+	secrets := make(map[string]secretData)
+
+	conf.Provider = applyEnv(conf.ProviderName, conf.Provider)
+	for k, v := range conf.Provider {
+		val, err := r.resolveValue(v, secrets)
+		if err != nil {
+			return api.Config{}, nil, errors.Wrapf(err, "provider.%s", k)
+		}
+		conf.Provider[k] = val
+	}
+
+	var err error
+	if conf.Userdata, err = r.resolveValue(conf.Userdata, secrets); err != nil {
+		return api.Config{}, nil, errors.Wrap(err, "userdata")
+	}
+
+	if conf.SupergiantV1Config != nil {
+		sgConf := *conf.SupergiantV1Config
+		v := reflect.ValueOf(&sgConf).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if v.Field(i).Kind() != reflect.String {
+				continue
+			}
+			val, err := r.resolveValue(v.Field(i).String(), secrets)
+			if err != nil {
+				return api.Config{}, nil, errors.Wrapf(err, "supergiantV1Config.%s", v.Type().Field(i).Name)
+			}
+			v.Field(i).SetString(val)
+		}
+		conf.SupergiantV1Config = &sgConf
+	}
+
+	versions := make(map[string]string, len(secrets))
+	for name, s := range secrets {
+		versions[name] = s.version
+	}
+	return conf, versions, nil
+}
+
+// changed returns true if any of the Secrets has a different resource version.
+func (r *secretResolver) changed(versions map[string]string) (bool, error) {
+	for name, version := range versions {
+		s, err := r.getSecret(name)
+		if err != nil {
+			return false, err
+		}
+		if s.version != version {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type secretData struct {
+	version string
+	data    map[string][]byte
+}
+
+func (r *secretResolver) resolveValue(val string, secrets map[string]secretData) (string, error) {
+	m := refRegexp.FindStringSubmatch(strings.TrimSpace(val))
+	if m == nil {
+		return val, nil
+	}
+
+	switch m[1] {
+	case refEnv:
+		envVal, ok := os.LookupEnv(m[2])
+		if !ok {
+			return "", errors.Errorf("environment variable %s not found", m[2])
+		}
+		return envVal, nil
+	case refSecret:
+		name, key, err := r.parseSecretRef(m[2])
+		if err != nil {
+			return "", err
+		}
+		s, ok := secrets[name]
+		if !ok {
+			if s, err = r.getSecret(name); err != nil {
+				return "", err
+			}
+			secrets[name] = s
+		}
+		data, ok := s.data[key]
+		if !ok {
+			return "", errors.Errorf("key %s not found in %s secret", key, name)
+		}
+		return string(data), nil
+	}
+	return "", errors.Wrap(ErrInvalidRef, val)
+}
+
+// parseSecretRef parses 'namespace/name/key' or 'name/key' references.
+func (r *secretResolver) parseSecretRef(ref string) (string, string, error) {
+	parts := strings.Split(ref, "/")
+	switch len(parts) {
+	case 2:
+		return r.defaultNamespace + "/" + parts[0], parts[1], nil
+	case 3:
+		return parts[0] + "/" + parts[1], parts[2], nil
+	}
+	return "", "", errors.Wrapf(ErrInvalidRef, "secret %q: namespace/name/key or name/key is expected", ref)
+}
+
+func (r *secretResolver) getSecret(nsName string) (secretData, error) {
+	if r.client == nil {
+		return secretData{}, errors.New("secrets client isn't configured")
+	}
+
+	parts := strings.SplitN(nsName, "/", 2)
+	s, err := r.client.Secrets(parts[0]).Get(parts[1], metav1.GetOptions{})
+	if err != nil {
+		return secretData{}, errors.Wrapf(err, "get %s secret", nsName)
+	}
+	return secretData{
+		version: s.ResourceVersion,
+		data:    s.Data,
+	}, nil
+}
+
+// applyEnv returns a copy of provider parameters overridden by environment variables.
+// A variable name is built from the EnvPrefix and a parameter name in upper case,
+// underscores are ignored on matching: CAPACITY_PROVIDER_AWS_KEYID -> awsKeyID.
+func applyEnv(providerName string, params map[string]string) map[string]string {
+	known := make(map[string]string)
+	for _, k := range factory.Keys(providerName) {
+		known[normalizeEnvKey(k)] = k
+	}
+
+	out := make(map[string]string, len(params))
+	for k, v := range params {
+		known[normalizeEnvKey(k)] = k
+		out[k] = v
+	}
+
+	envPrefix := EnvPrefix + "_PROVIDER_"
+	for _, env := range os.Environ() {
+		kv := strings.SplitN(env, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], envPrefix) || kv[1] == "" {
+			continue
+		}
+		if k, ok := known[normalizeEnvKey(strings.TrimPrefix(kv[0], envPrefix))]; ok {
+			out[k] = kv[1]
+		}
+	}
+	return out
+}
+
+func normalizeEnvKey(k string) string {
+	return strings.ToLower(strings.Replace(k, "_", "", -1))
+}
@@ -0,0 +1,111 @@
+package kubescaler
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider/aws"
+)
+
+type fakeSecrets map[string]*corev1.Secret
+
+func (f fakeSecrets) Secrets(namespace string) v1.SecretInterface {
+	return fakeSecretInterface{namespace: namespace, secrets: f}
+}
+
+type fakeSecretInterface struct {
+	v1.SecretInterface
+	namespace string
+	secrets   fakeSecrets
+}
+
+func (f fakeSecretInterface) Get(name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	s, ok := f.secrets[f.namespace+"/"+name]
+	if !ok {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}
+	return s, nil
+}
+
+func TestSecretResolver(t *testing.T) {
+	secrets := fakeSecrets{
+		"kube-system/aws": {
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"},
+			Data:       map[string][]byte{"secretKey": []byte("secret")},
+		},
+		"capacity/kube": {
+			ObjectMeta: metav1.ObjectMeta{ResourceVersion: "7"},
+			Data:       map[string][]byte{"password": []byte("password")},
+		},
+	}
+	r := newSecretResolver(secrets, "capacity")
+
+	require.Nil(t, os.Setenv("TEST_AWS_KEY_ID", "id"))
+	defer os.Unsetenv("TEST_AWS_KEY_ID")
+
+	conf := api.Config{
+		ProviderName: aws.Name,
+		Provider: map[string]string{
+			aws.KeyID:     "${env:TEST_AWS_KEY_ID}",
+			aws.SecretKey: "${secret:kube-system/aws/secretKey}",
+			aws.Region:    "us-west-1",
+		},
+		SupergiantV1Config: &api.SupergiantV1UserdataVars{
+			KubeAPIPassword: "${secret:kube/password}",
+		},
+	}
+
+	resolved, versions, err := r.resolve(conf)
+	require.Nil(t, err)
+	require.Equal(t, "id", resolved.Provider[aws.KeyID])
+	require.Equal(t, "secret", resolved.Provider[aws.SecretKey])
+	require.Equal(t, "us-west-1", resolved.Provider[aws.Region])
+	require.Equal(t, "password", resolved.SupergiantV1Config.KubeAPIPassword)
+	require.Equal(t, map[string]string{"kube-system/aws": "1", "capacity/kube": "7"}, versions)
+
+	// references should be kept in the original config
+	require.Equal(t, "${secret:kube-system/aws/secretKey}", conf.Provider[aws.SecretKey])
+	require.Equal(t, "${secret:kube/password}", conf.SupergiantV1Config.KubeAPIPassword)
+
+	changed, err := r.changed(versions)
+	require.Nil(t, err)
+	require.False(t, changed)
+
+	secrets["kube-system/aws"].ResourceVersion = "2"
+	changed, err = r.changed(versions)
+	require.Nil(t, err)
+	require.True(t, changed)
+
+	conf.Provider[aws.SecretKey] = "${secret:kube-system/aws/unknown}"
+	_, _, err = r.resolve(conf)
+	require.NotNil(t, err)
+
+	conf.Provider[aws.SecretKey] = "${secret:unknown}"
+	_, _, err = r.resolve(conf)
+	require.NotNil(t, err)
+}
+
+func TestApplyEnv(t *testing.T) {
+	require.Nil(t, os.Setenv("CAPACITY_PROVIDER_AWS_SECRETKEY", "secret"))
+	require.Nil(t, os.Setenv("CAPACITY_PROVIDER_AWS_SUBNET_ID", "subnet"))
+	defer os.Unsetenv("CAPACITY_PROVIDER_AWS_SECRETKEY")
+	defer os.Unsetenv("CAPACITY_PROVIDER_AWS_SUBNET_ID")
+
+	// should not panic on empty provider parameters
+	params := applyEnv(aws.Name, nil)
+	require.Equal(t, "secret", params[aws.SecretKey])
+	require.Equal(t, "subnet", params[aws.SubnetID])
+
+	in := map[string]string{aws.Region: "us-west-1"}
+	params = applyEnv(aws.Name, in)
+	require.Equal(t, "us-west-1", params[aws.Region])
+	require.Equal(t, "secret", params[aws.SecretKey])
+	require.Len(t, in, 1)
+}
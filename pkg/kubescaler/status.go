@@ -0,0 +1,114 @@
+package kubescaler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/kubescaler/workers"
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+// statusWriter is implemented by config files that are able to report the scaler
+// state, e.g. the CapacityConfig resource.
+type statusWriter interface {
+	WriteStatus(status api.Status) error
+}
+
+// scalerStatus keeps the last observed scaler state.
+type scalerStatus struct {
+	mu     sync.RWMutex
+	status api.Status
+	// configErr is set if the last external config change hasn't been applied
+	configErr error
+	// runErr is the last scan error
+	runErr error
+}
+
+// setLimits keeps workers count limits in effect.
+func (s *scalerStatus) setLimits(cfg api.Config, schedule *api.Schedule, scanTime time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.WorkersCountMin = cfg.WorkersCountMin
+	s.status.WorkersCountMax = cfg.WorkersCountMax
+	s.status.Schedule = ""
+	if schedule != nil {
+		s.status.Schedule = schedule.Name
+		if s.status.Schedule == "" {
+			s.status.Schedule = schedule.Cron
+		}
+	}
+	s.status.LastScanTime = &scanTime
+}
+
+func (s *scalerStatus) setWorkers(count, ready, shortfall int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Workers = count
+	s.status.ReadyWorkers = ready
+	s.status.Shortfall = shortfall
+}
+
+func (s *scalerStatus) setConfigErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configErr = err
+}
+
+func (s *scalerStatus) setRunErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runErr = err
+}
+
+// get returns the status with the last error, a config error is returned first,
+// as the scaler keeps running with the previous config in that case.
+func (s *scalerStatus) get() api.Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := s.status
+	switch {
+	case s.configErr != nil:
+		out.LastError = s.configErr.Error()
+	case s.runErr != nil:
+		out.LastError = s.runErr.Error()
+	}
+	return out
+}
+
+// Status returns the observed scaler state.
+func (s *Kubescaler) Status() api.Status {
+	out := s.status.get()
+	out.Backoff = s.backoff.list(time.Now())
+	return out
+}
+
+// writeStatus reports the scaler state to the config file if it supports this.
+func (s *Kubescaler) writeStatus() {
+	w, ok := s.configManager.file.(statusWriter)
+	if !ok {
+		return
+	}
+
+	if err := w.WriteStatus(s.Status()); err != nil {
+		log.Errorf("kubescaler: %v", err)
+	}
+}
+
+// countWorkers returns a number of pending and running workers and a number of ones with ready nodes.
+// The list comes from ListWorkers, so masters are already filtered out by the node role label.
+func countWorkers(workerList *api.WorkerList) (int, int) {
+	count, ready := 0, 0
+	for _, w := range workerList.Items {
+		if w.MachineState != machineStatePending && w.MachineState != machineStateRunning {
+			continue
+		}
+		count++
+		if w.NodeState == workers.NodeStateReady {
+			ready++
+		}
+	}
+	return count, ready
+}
@@ -0,0 +1,15 @@
+package kubescaler
+
+import (
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/provider/factory"
+)
+
+// ValidateConfig checks the config against the selected provider's schema and machine
+// catalog. It returns an *api.ValidationError with a list of invalid fields.
+func ValidateConfig(conf api.Config) error {
+	errs := conf.ValidateFields()
+	// provider parameters could be set with environment variables
+	errs = append(errs, factory.Validate(conf.ProviderName, applyEnv(conf.ProviderName, conf.Provider), conf.MachineTypes)...)
+	return api.NewValidationError(errs)
+}
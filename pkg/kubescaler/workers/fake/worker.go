@@ -55,6 +55,14 @@ func (m *Manager) CreateWorker(ctx context.Context, mtype string) (*api.Worker,
 	}, m.err
 }
 
+func (m *Manager) CreateWorkerInZone(ctx context.Context, mtype, zone string) (*api.Worker, error) {
+	return m.CreateWorker(ctx, mtype)
+}
+
+func (m *Manager) Zones() []string {
+	return nil
+}
+
 func (m *Manager) GetWorker(ctx context.Context, id string) (*api.Worker, error) {
 	return &api.Worker{
 		ClusterName:       m.clusterName,
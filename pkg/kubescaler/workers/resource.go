@@ -0,0 +1,258 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/capacity/pkg/api"
+)
+
+// Worker resource parameters:
+const (
+	ResourceGroup   = "capacity.supergiant.io"
+	ResourceVersion = "v1alpha1"
+	ResourceKind    = "Worker"
+	ResourcePlural  = "workers"
+
+	// LabelManagedBy marks resources created by capacity, others aren't removed.
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	ManagedBy      = "capacity"
+)
+
+// Resource is a Worker custom resource that mirrors a provider machine.
+// The spec can be edited to reserve the worker, the status is kept in sync by capacity.
+type Resource struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ResourceSpec   `json:"spec"`
+	Status ResourceStatus `json:"status,omitempty"`
+}
+
+// ResourceSpec describes a desired worker state.
+type ResourceSpec struct {
+	// MachineType is type of virtual machine (eg. 't2.micro' for AWS).
+	MachineType string `json:"machineType"`
+	// Reserved prevents downscaling of the worker.
+	Reserved bool `json:"reserved"`
+}
+
+// ResourceStatus is an observed machine and node state.
+type ResourceStatus struct {
+	// ObservedGeneration is the latest spec generation applied to the worker.
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+	MachineID          string `json:"machineID,omitempty"`
+	MachineName        string `json:"machineName,omitempty"`
+	MachineState       string `json:"machineState,omitempty"`
+	NodeName           string `json:"nodeName,omitempty"`
+	NodeState          string `json:"nodeState,omitempty"`
+	// CreationTimestamp is a time when the machine was created.
+	CreationTimestamp metav1.Time `json:"creationTimestamp,omitempty"`
+}
+
+type resourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Resource `json:"items"`
+}
+
+// ResourceName returns a Worker resource name for the machine.
+func ResourceName(machineID string) string {
+	return strings.ToLower(machineID)
+}
+
+// Mirror maintains a Worker resource per machine.
+type Mirror struct {
+	client rest.Interface
+}
+
+// NewMirror returns a mirror that uses the rest client to manage Worker resources.
+func NewMirror(client rest.Interface) *Mirror {
+	return &Mirror{
+		client: client,
+	}
+}
+
+// Sync creates resources for new machines, removes them for deleted ones and updates
+// the status of others. A worker is reserved or released if the resource spec has been
+// changed since the last sync, otherwise the spec follows the worker.
+func (m *Mirror) Sync(ctx context.Context, wi WInterface) error {
+	workerList, err := wi.ListWorkers(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list workers")
+	}
+	resources, err := m.list()
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, 0)
+	seen := make(map[string]bool, len(workerList.Items))
+	for _, w := range workerList.Items {
+		name := ResourceName(w.MachineID)
+		seen[name] = true
+
+		res, ok := resources[name]
+		if !ok {
+			err = m.create(w)
+		} else {
+			err = m.sync(ctx, wi, res, w)
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "worker %s", w.MachineID))
+		}
+	}
+
+	for name := range resources {
+		if seen[name] {
+			continue
+		}
+		err = m.client.Delete().AbsPath(m.path(name)).Do().Error()
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, errors.Wrapf(err, "delete worker resource %s", name))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func (m *Mirror) sync(ctx context.Context, wi WInterface, res *Resource, w *api.Worker) error {
+	if res.Generation != res.Status.ObservedGeneration && res.Spec.Reserved != w.Reserved {
+		reserved, err := wi.ReserveWorker(ctx, &api.Worker{
+			MachineID: w.MachineID,
+			Reserved:  res.Spec.Reserved,
+		})
+		if err != nil {
+			return errors.Wrap(err, "reserve")
+		}
+		w = reserved
+	}
+
+	var err error
+	if spec := specFrom(w); res.Spec != spec {
+		res.Spec = spec
+		if res, err = m.put(m.path(res.Name), res); err != nil {
+			return errors.Wrap(err, "update worker resource")
+		}
+	}
+
+	return m.updateStatus(res, w)
+}
+
+func (m *Mirror) create(w *api.Worker) error {
+	res := &Resource{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: ResourceGroup + "/" + ResourceVersion,
+			Kind:       ResourceKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ResourceName(w.MachineID),
+			Labels: map[string]string{LabelManagedBy: ManagedBy},
+		},
+		Spec: specFrom(w),
+	}
+	body, err := json.Marshal(res)
+	if err != nil {
+		return errors.Wrap(err, "encode worker resource")
+	}
+	raw, err := m.client.Post().AbsPath(m.path("")).Body(body).Do().Raw()
+	if err != nil {
+		return errors.Wrap(err, "create worker resource")
+	}
+	if res, err = decodeResource(raw); err != nil {
+		return err
+	}
+
+	return m.updateStatus(res, w)
+}
+
+func (m *Mirror) updateStatus(res *Resource, w *api.Worker) error {
+	status := statusFrom(w, res.Generation)
+	if statusEqual(res.Status, status) {
+		return nil
+	}
+	res.Status = status
+	if _, err := m.put(m.path(res.Name, "status"), res); err != nil {
+		return errors.Wrap(err, "update worker resource status")
+	}
+	return nil
+}
+
+func (m *Mirror) list() (map[string]*Resource, error) {
+	raw, err := m.client.Get().AbsPath(m.path("")).
+		Param("labelSelector", LabelManagedBy+"="+ManagedBy).Do().Raw()
+	if err != nil {
+		return nil, errors.Wrap(err, "list worker resources")
+	}
+
+	list := &resourceList{}
+	if err = json.Unmarshal(raw, list); err != nil {
+		return nil, errors.Wrap(err, "decode worker resources")
+	}
+	out := make(map[string]*Resource, len(list.Items))
+	for i := range list.Items {
+		out[list.Items[i].Name] = &list.Items[i]
+	}
+	return out, nil
+}
+
+// put keeps the resourceVersion, so a resource modified after the list call isn't overwritten.
+func (m *Mirror) put(absPath string, res *Resource) (*Resource, error) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		return nil, errors.Wrap(err, "encode worker resource")
+	}
+	raw, err := m.client.Put().AbsPath(absPath).Body(body).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	return decodeResource(raw)
+}
+
+func (m *Mirror) path(name string, subresources ...string) string {
+	return path.Join(append([]string{"/apis", ResourceGroup, ResourceVersion, ResourcePlural, name}, subresources...)...)
+}
+
+func decodeResource(raw []byte) (*Resource, error) {
+	res := &Resource{}
+	if err := json.Unmarshal(raw, res); err != nil {
+		return nil, errors.Wrap(err, "decode worker resource")
+	}
+	return res, nil
+}
+
+func specFrom(w *api.Worker) ResourceSpec {
+	return ResourceSpec{
+		MachineType: w.MachineType,
+		Reserved:    w.Reserved,
+	}
+}
+
+func statusFrom(w *api.Worker, generation int64) ResourceStatus {
+	return ResourceStatus{
+		ObservedGeneration: generation,
+		MachineID:          w.MachineID,
+		MachineName:        w.MachineName,
+		MachineState:       w.MachineState,
+		NodeName:           w.NodeName,
+		NodeState:          w.NodeState,
+		// resource timestamps have a second precision
+		CreationTimestamp: metav1.NewTime(w.CreationTimestamp.Truncate(time.Second)),
+	}
+}
+
+func statusEqual(a, b ResourceStatus) bool {
+	ta, tb := a.CreationTimestamp, b.CreationTimestamp
+	a.CreationTimestamp, b.CreationTimestamp = metav1.Time{}, metav1.Time{}
+	return a == b && ta.Equal(&tb)
+}
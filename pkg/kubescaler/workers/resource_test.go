@@ -0,0 +1,151 @@
+package workers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/kubescaler/workers"
+	"github.com/supergiant/capacity/pkg/kubescaler/workers/fake"
+)
+
+// fakeWorkerAPI serves Worker resources, generation is increased on spec changes only.
+type fakeWorkerAPI struct {
+	mu        sync.Mutex
+	resources map[string]*workers.Resource
+	version   int
+}
+
+func (a *fakeWorkerAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/apis/capacity.supergiant.io/v1alpha1/workers"), "/")
+	name := ""
+	if len(parts) > 1 {
+		name = parts[1]
+	}
+	in := &workers.Resource{}
+	if body, _ := ioutil.ReadAll(r.Body); len(body) > 0 {
+		json.Unmarshal(body, in)
+	}
+
+	var out interface{}
+	switch r.Method {
+	case http.MethodGet:
+		list := struct {
+			Items []workers.Resource `json:"items"`
+		}{}
+		for _, res := range a.resources {
+			list.Items = append(list.Items, *res)
+		}
+		out = list
+	case http.MethodPost:
+		in.Generation = 1
+		in.Status = workers.ResourceStatus{}
+		a.resources[in.Name] = in
+		out = a.update(in)
+	case http.MethodPut:
+		res := a.resources[name]
+		if len(parts) > 2 && parts[2] == "status" {
+			res.Status = in.Status
+		} else if res.Spec != in.Spec {
+			res.Spec = in.Spec
+			res.Generation++
+		}
+		out = a.update(res)
+	case http.MethodDelete:
+		delete(a.resources, name)
+		out = metav1.Status{Status: metav1.StatusSuccess}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+func (a *fakeWorkerAPI) update(res *workers.Resource) *workers.Resource {
+	a.version++
+	res.ResourceVersion = strconv.Itoa(a.version)
+	return res
+}
+
+// reservingManager keeps worker reservations and counts ReserveWorker calls.
+type reservingManager struct {
+	*fake.Manager
+	reserved     map[string]bool
+	reserveCalls int
+}
+
+func (m *reservingManager) ListWorkers(ctx context.Context) (*api.WorkerList, error) {
+	list, err := m.Manager.ListWorkers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range list.Items {
+		w.Reserved = m.reserved[w.MachineID]
+	}
+	return list, nil
+}
+
+func (m *reservingManager) ReserveWorker(ctx context.Context, w *api.Worker) (*api.Worker, error) {
+	m.reserveCalls++
+	m.reserved[w.MachineID] = w.Reserved
+	return m.Manager.ReserveWorker(ctx, w)
+}
+
+func TestMirrorSync(t *testing.T) {
+	server := &fakeWorkerAPI{
+		resources: map[string]*workers.Resource{
+			"i-removed": {ObjectMeta: metav1.ObjectMeta{Name: "i-removed"}},
+		},
+	}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	client, err := corev1client.NewForConfig(&rest.Config{Host: srv.URL})
+	require.Nil(t, err)
+	m := workers.NewMirror(client.RESTClient())
+	wm := &reservingManager{Manager: fake.NewManager(nil), reserved: make(map[string]bool)}
+
+	require.Nil(t, m.Sync(context.Background(), wm))
+	require.Len(t, server.resources, 2)
+	res := server.resources[workers.ResourceName("i-01e9c47fede75cb9a")]
+	require.NotNil(t, res)
+	require.Equal(t, workers.ResourceSpec{MachineType: "m4.large"}, res.Spec)
+	require.Equal(t, "running", res.Status.MachineState)
+	require.Equal(t, res.Generation, res.Status.ObservedGeneration)
+
+	// the worker is reserved by editing the spec
+	res.Spec.Reserved = true
+	res.Generation++
+	require.Nil(t, m.Sync(context.Background(), wm))
+	require.True(t, res.Spec.Reserved)
+	require.Equal(t, res.Generation, res.Status.ObservedGeneration)
+	require.Equal(t, 1, wm.reserveCalls)
+
+	// the reservation is kept on the next syncs
+	require.Nil(t, m.Sync(context.Background(), wm))
+	require.True(t, res.Spec.Reserved)
+	require.Equal(t, res.Generation, res.Status.ObservedGeneration)
+	require.Equal(t, 1, wm.reserveCalls)
+
+	// the spec follows the worker if it hasn't been edited
+	wm.reserved["i-01e9c47fede75cb9a"] = false
+	require.Nil(t, m.Sync(context.Background(), wm))
+	require.False(t, res.Spec.Reserved)
+	require.Equal(t, res.Generation, res.Status.ObservedGeneration)
+	require.Equal(t, "i-01e9c47fede75cb9a", res.Status.MachineID)
+	require.Equal(t, 1, wm.reserveCalls)
+}
@@ -41,6 +41,8 @@ var (
 type WInterface interface {
 	MachineTypes() []*provider.MachineType
 	CreateWorker(ctx context.Context, mtype string) (*api.Worker, error)
+	CreateWorkerInZone(ctx context.Context, mtype, zone string) (*api.Worker, error)
+	Zones() []string
 	GetWorker(ctx context.Context, id string) (*api.Worker, error)
 	ListWorkers(ctx context.Context) (*api.WorkerList, error)
 	DeleteWorker(ctx context.Context, nodeName, id string) (*api.Worker, error)
@@ -89,6 +91,25 @@ func (m *Manager) CreateWorker(ctx context.Context, mtype string) (*api.Worker,
 	return m.workerFrom(machine, corev1.Node{}), nil
 }
 
+// CreateWorkerInZone creates a worker in the availability zone, it should be one of Zones.
+func (m *Manager) CreateWorkerInZone(ctx context.Context, mtype, zone string) (*api.Worker, error) {
+	config := provider.Config{provider.ZoneKey: zone}
+	machine, err := m.provider.CreateMachine(ctx, m.workerName(), mtype, ClusterRole, m.userdata, config)
+	if err != nil {
+		return nil, err
+	}
+	return m.workerFrom(machine, corev1.Node{}), nil
+}
+
+// Zones returns availability zones workers could be created in, it's empty if the provider
+// doesn't support them.
+func (m *Manager) Zones() []string {
+	if z, ok := m.provider.(provider.Zoner); ok {
+		return z.Zones()
+	}
+	return nil
+}
+
 func (m *Manager) MachineTypes() []*provider.MachineType {
 	return m.machineTypes
 }
@@ -107,6 +128,12 @@ func (m *Manager) GetWorker(ctx context.Context, id string) (*api.Worker, error)
 	return m.workerFrom(machine, node), nil
 }
 
+// IsMasterNode returns true if the node has the master role label, such nodes aren't workers.
+func IsMasterNode(node *corev1.Node) bool {
+	_, ok := node.Labels[nodeLabelRole]
+	return ok
+}
+
 func (m *Manager) ListWorkers(ctx context.Context) (*api.WorkerList, error) {
 	machines, err := m.provider.Machines(ctx)
 	if err != nil {
@@ -121,7 +148,7 @@ func (m *Manager) ListWorkers(ctx context.Context) (*api.WorkerList, error) {
 	workers := make([]*api.Worker, 0)
 	for i := range machines {
 		node = nodesMap[machines[i].ID]
-		if _, ok := node.Labels[nodeLabelRole]; ok {
+		if IsMasterNode(&node) {
 			continue
 		}
 		workers = append(workers, m.workerFrom(machines[i], node))
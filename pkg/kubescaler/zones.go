@@ -0,0 +1,106 @@
+package kubescaler
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+const (
+	labelTopologyZone = "topology.kubernetes.io/zone"
+	// multi-zone volumes are labeled with zones joined by the delimiter, e.g. 'us-east-1a__us-east-1b'
+	zoneLabelDelimiter = "__"
+)
+
+// pvZones returns zones the volume is available in from its labels or node affinity,
+// nil is returned if it's available in any zone.
+func pvZones(pv *corev1.PersistentVolume) []string {
+	for _, key := range []string{corev1.LabelZoneFailureDomain, labelTopologyZone} {
+		if v := pv.Labels[key]; v != "" {
+			return strings.Split(v, zoneLabelDelimiter)
+		}
+	}
+
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return nil
+	}
+	var zones []string
+	// node selector terms are ORed, so a volume is available in zones of any of them
+	for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+		for _, expr := range term.MatchExpressions {
+			isZone := expr.Key == corev1.LabelZoneFailureDomain || expr.Key == labelTopologyZone
+			if isZone && expr.Operator == corev1.NodeSelectorOpIn {
+				zones = append(zones, expr.Values...)
+			}
+		}
+	}
+	return zones
+}
+
+// intersectZones returns zones of both lists, a nil list means any zone.
+func intersectZones(a, b []string) []string {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	out := make([]string, 0)
+	for _, za := range a {
+		for _, zb := range b {
+			if za == zb {
+				out = append(out, za)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// podVolumeZones returns zones the pod is able to run in with its bound volumes, nil is returned
+// if it could run in any zone. Volumes that can't be got are skipped.
+func podVolumeZones(pod *corev1.Pod, getPV func(namespace, claim string) (*corev1.PersistentVolume, error)) []string {
+	var zones []string
+	for _, v := range pod.Spec.Volumes {
+		if v.PersistentVolumeClaim == nil {
+			continue
+		}
+		pv, err := getPV(pod.Namespace, v.PersistentVolumeClaim.ClaimName)
+		if err != nil {
+			log.Debugf("kubescaler: volume zones: %s/%s: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		if pv == nil {
+			// the claim isn't bound yet, a volume will be provisioned for the node
+			continue
+		}
+		zones = intersectZones(zones, pvZones(pv))
+	}
+	return zones
+}
+
+// volumeZones returns zones of pods with zonal volumes, claims and volumes are got from the lister cache.
+func (s *Kubescaler) volumeZones(pods []*corev1.Pod) map[types.UID][]string {
+	lister := s.listerRegistry.VolumeLister()
+	getPV := func(namespace, claim string) (*corev1.PersistentVolume, error) {
+		pvc, err := lister.GetClaim(namespace, claim)
+		if err != nil {
+			return nil, err
+		}
+		if pvc.Spec.VolumeName == "" {
+			return nil, nil
+		}
+		return lister.GetVolume(pvc.Spec.VolumeName)
+	}
+
+	out := make(map[types.UID][]string)
+	for _, pod := range pods {
+		if zones := podVolumeZones(pod, getPV); zones != nil {
+			out[pod.UID] = zones
+		}
+	}
+	return out
+}
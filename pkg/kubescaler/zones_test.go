@@ -0,0 +1,177 @@
+package kubescaler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/supergiant/capacity/pkg/kubernetes/listers"
+	"github.com/supergiant/capacity/pkg/provider"
+)
+
+func testPV(labels map[string]string, affinityZones ...string) *corev1.PersistentVolume {
+	pv := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Labels: labels},
+	}
+	if len(affinityZones) > 0 {
+		pv.Spec.NodeAffinity = &corev1.VolumeNodeAffinity{
+			Required: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: labelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: affinityZones},
+						},
+					},
+				},
+			},
+		}
+	}
+	return pv
+}
+
+func TestPVZones(t *testing.T) {
+	tcs := []struct {
+		pv            *corev1.PersistentVolume
+		expectedZones []string
+	}{
+		{
+			pv: testPV(nil),
+		},
+		{
+			pv:            testPV(map[string]string{corev1.LabelZoneFailureDomain: "us-east-1a"}),
+			expectedZones: []string{"us-east-1a"},
+		},
+		{
+			pv:            testPV(map[string]string{labelTopologyZone: "us-east-1a__us-east-1b"}),
+			expectedZones: []string{"us-east-1a", "us-east-1b"},
+		},
+		{
+			pv:            testPV(nil, "us-east-1c"),
+			expectedZones: []string{"us-east-1c"},
+		},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expectedZones, pvZones(tc.pv), "TC#%d", i+1)
+	}
+}
+
+func TestPodVolumeZones(t *testing.T) {
+	pvs := map[string]*corev1.PersistentVolume{
+		"data-a":  testPV(map[string]string{corev1.LabelZoneFailureDomain: "us-east-1a"}),
+		"data-ab": testPV(map[string]string{corev1.LabelZoneFailureDomain: "us-east-1a__us-east-1b"}),
+		"data-b":  testPV(nil, "us-east-1b"),
+		"shared":  testPV(nil),
+		"pending": nil,
+	}
+	getPV := func(namespace, claim string) (*corev1.PersistentVolume, error) {
+		pv, ok := pvs[claim]
+		if !ok {
+			return nil, errFake
+		}
+		return pv, nil
+	}
+	podWithClaims := func(claims ...string) *corev1.Pod {
+		pod := testPod("", "1", "1Gi")
+		for _, c := range claims {
+			pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: c},
+				},
+			})
+		}
+		return pod
+	}
+
+	tcs := []struct {
+		pod           *corev1.Pod
+		expectedZones []string
+	}{
+		{
+			pod: podWithClaims(),
+		},
+		{
+			pod: podWithClaims("shared", "pending", "unknown"),
+		},
+		{
+			pod:           podWithClaims("data-ab", "shared"),
+			expectedZones: []string{"us-east-1a", "us-east-1b"},
+		},
+		{
+			pod:           podWithClaims("data-ab", "data-b"),
+			expectedZones: []string{"us-east-1b"},
+		},
+		{
+			pod:           podWithClaims("data-a", "data-b"),
+			expectedZones: []string{},
+		},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expectedZones, podVolumeZones(tc.pod, getPV), "TC#%d", i+1)
+	}
+}
+
+func TestFilterZones(t *testing.T) {
+	podAny, podA, podB, podC := podWithRequests, podWithRequests, podWithRequests, podWithRequests
+	podAny.UID, podA.UID, podB.UID, podC.UID = "any", "a", "b", "c"
+
+	filter := newPodFilter(validTestConfig()).withZones([]string{"us-east-1a", "us-east-1b"}, map[types.UID][]string{
+		podA.UID: {"us-east-1a"},
+		podB.UID: {"us-east-1b", "us-east-1c"},
+		podC.UID: {"us-east-1c"},
+	})
+
+	pods := []*corev1.Pod{&podAny, &podA, &podB, &podC}
+	toScale, ignored := filterPods(pods, filter, []*provider.MachineType{&allowedMachine}, currentTime)
+	require.Equal(t, []*corev1.Pod{&podAny, &podA, &podB}, toScale)
+	require.Equal(t, []string{"/podWithRequests=volume-zone-unreachable"}, ignored)
+
+	zone, inZone := filter.zoneToScale(toScale)
+	require.Equal(t, "us-east-1a", zone)
+	require.Equal(t, []*corev1.Pod{&podAny, &podA}, inZone)
+
+	zone, inZone = filter.zoneToScale([]*corev1.Pod{&podAny, &podB})
+	require.Equal(t, "us-east-1b", zone)
+	require.Equal(t, []*corev1.Pod{&podAny, &podB}, inZone)
+
+	// zones aren't checked if the provider doesn't support them
+	toScale, _ = filterPods(pods, newPodFilter(validTestConfig()), []*provider.MachineType{&allowedMachine}, currentTime)
+	require.Len(t, toScale, len(pods))
+}
+
+// fakeVolumeLister serves claims bound to volumes of the same name.
+type fakeVolumeLister map[string]*corev1.PersistentVolume
+
+func (l fakeVolumeLister) GetClaim(namespace, name string) (*corev1.PersistentVolumeClaim, error) {
+	pvc := &corev1.PersistentVolumeClaim{}
+	if _, ok := l[name]; ok {
+		pvc.Spec.VolumeName = name
+	}
+	return pvc, nil
+}
+
+func (l fakeVolumeLister) GetVolume(name string) (*corev1.PersistentVolume, error) {
+	return l[name], nil
+}
+
+func TestVolumeZones(t *testing.T) {
+	ks := &Kubescaler{
+		listerRegistry: listers.NewRegistry(nil, nil, fakeVolumeLister{
+			"data-a": testPV(map[string]string{corev1.LabelZoneFailureDomain: "us-east-1a"}),
+		}),
+	}
+	podA, podPending := podWithRequests, podWithRequests
+	podA.UID, podPending.UID = "a", "pending"
+	podA.Spec.Volumes = []corev1.Volume{{
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "data-a"}},
+	}}
+	podPending.Spec.Volumes = []corev1.Volume{{
+		VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "unbound"}},
+	}}
+
+	require.Equal(t, map[types.UID][]string{"a": {"us-east-1a"}}, ks.volumeZones([]*corev1.Pod{&podA, &podPending}))
+}
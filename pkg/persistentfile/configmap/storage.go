@@ -1,6 +1,7 @@
 package configmap
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -18,6 +19,7 @@ var (
 	ErrInvalidConfigMap       = errors.New("configMap name and namespase should be provided")
 	ErrInvalidConfigMapKey    = errors.New("data key for configMap should be provided")
 	ErrInvalidConfigMapClient = errors.New("configMap client should be provided")
+	ErrConflict               = errors.New("configMap has been changed")
 )
 
 // IsNotExist returns a boolean indicating whether the error is known to report
@@ -26,6 +28,12 @@ func IsNotExist(err error) bool {
 	return apierrors.IsNotFound(err) || errors.Cause(err) == ErrKeyNotFound
 }
 
+// IsConflict returns a boolean indicating whether the ConfigMap resourceVersion
+// doesn't match.
+func IsConflict(err error) bool {
+	return apierrors.IsConflict(errors.Cause(err)) || errors.Cause(err) == ErrConflict
+}
+
 // CMFile represents a file on kubernetes ConfigMap.
 type CMFile struct {
 	cmName      string
@@ -56,35 +64,51 @@ func New(cmName, ns, key string, client v1.ConfigMapsGetter) (*CMFile, error) {
 	}, nil
 }
 
+// Sibling returns a file on the same namespace ConfigMap with the name suffix added.
+// The ConfigMap is created on the first write.
+func (f CMFile) Sibling(name string) (*CMFile, error) {
+	sibling, err := New(f.cmName+"-"+name, f.cmNamespace, f.key, f.client)
+	if err != nil {
+		return nil, err
+	}
+	sibling.cmCreate = true
+	return sibling, nil
+}
+
+// Name returns the ConfigMap key.
+func (f CMFile) Name() string {
+	return f.key
+}
+
 // Info describes a file stored on kubernetes ConfigMap.
 func (f CMFile) Info() string {
 	return fmt.Sprintf(`%s key, %s/%s ConfigMap`, f.key, f.cmNamespace, f.cmName)
 }
 
-// Read reads a file from the ConfigMap.
-func (f CMFile) Read() ([]byte, error) {
+// Read reads a file from the ConfigMap. The ConfigMap resourceVersion is used as a version.
+func (f CMFile) Read() ([]byte, string, error) {
 	cm, err := f.client.ConfigMaps(f.cmNamespace).Get(f.cmName, metav1.GetOptions{})
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	data, ok := cm.Data[f.key]
 	if !ok {
-		return nil, ErrKeyNotFound
+		return nil, "", ErrKeyNotFound
 	}
 
-	return []byte(data), nil
+	return []byte(data), cm.ResourceVersion, nil
 }
 
 // Write stores data to the ConfigMap. If provided ConfigMap doesn't exist it creates
-// the new one.
-func (f CMFile) Write(data []byte) error {
-	_, err := f.client.ConfigMaps(f.cmNamespace).Get(f.cmName, metav1.GetOptions{})
+// the new one. A not empty version should match the ConfigMap resourceVersion.
+func (f CMFile) Write(data []byte, version string) (string, error) {
+	cm, err := f.client.ConfigMaps(f.cmNamespace).Get(f.cmName, metav1.GetOptions{})
 	if err != nil {
 		// ensure ConfigMap has created
 		// TODO: do we need to ensure this? (similar to os.O_CREATE param for file)
 		if f.cmCreate && apierrors.IsNotFound(err) {
-			_, err = f.client.ConfigMaps(f.cmNamespace).Create(&corev1.ConfigMap{
+			cm, err = f.client.ConfigMaps(f.cmNamespace).Create(&corev1.ConfigMap{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      f.cmName,
 					Namespace: f.cmNamespace,
@@ -93,14 +117,35 @@ func (f CMFile) Write(data []byte) error {
 					f.key: string(data),
 				},
 			})
+			if err == nil {
+				return cm.ResourceVersion, nil
+			}
 		}
-		return err
+		return "", err
+	}
+
+	if version == "" {
+		version = cm.ResourceVersion
+	} else if version != cm.ResourceVersion {
+		return "", errors.Wrapf(ErrConflict, "%s version %q", f.Info(), version)
 	}
 
-	_, err = f.client.ConfigMaps(f.cmNamespace).Patch(
-		f.cmName,
-		types.MergePatchType,
-		[]byte(fmt.Sprintf(`{"data":{%q:%q}}`, f.key, string(data))),
-	)
-	return errors.Wrap(err, "patch configMap")
+	// resourceVersion is a precondition for the patch, so a ConfigMap modified
+	// after the Get call isn't overwritten
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]string{"resourceVersion": version},
+		"data":     map[string]string{f.key: string(data)},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "encode patch")
+	}
+
+	cm, err = f.client.ConfigMaps(f.cmNamespace).Patch(f.cmName, types.MergePatchType, patch)
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			return "", errors.Wrapf(ErrConflict, "%s version %q", f.Info(), version)
+		}
+		return "", errors.Wrap(err, "patch configMap")
+	}
+	return cm.ResourceVersion, nil
 }
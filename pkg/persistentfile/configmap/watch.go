@@ -0,0 +1,49 @@
+package configmap
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Watch calls onChange each time the ConfigMap has been changed.
+func (f CMFile) Watch(stopCh <-chan struct{}, onChange func(data []byte, version string)) error {
+	selector := fields.OneTermEqualSelector("metadata.name", f.cmName).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.FieldSelector = selector
+			return f.client.ConfigMaps(f.cmNamespace).List(opts)
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.FieldSelector = selector
+			return f.client.ConfigMaps(f.cmNamespace).Watch(opts)
+		},
+	}
+
+	_, last, _ := f.Read()
+	send := func(obj interface{}) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok || cm.ResourceVersion == last {
+			return
+		}
+		data, ok := cm.Data[f.key]
+		if !ok {
+			return
+		}
+		last = cm.ResourceVersion
+		onChange([]byte(data), cm.ResourceVersion)
+	}
+
+	_, controller := cache.NewInformer(lw, &corev1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: send,
+		UpdateFunc: func(_, obj interface{}) {
+			send(obj)
+		},
+	})
+	go controller.Run(stopCh)
+
+	return nil
+}
@@ -0,0 +1,274 @@
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/capacity/pkg/api"
+	"github.com/supergiant/capacity/pkg/persistentfile/configmap"
+)
+
+// CapacityConfig resource parameters:
+const (
+	Group   = "capacity.supergiant.io"
+	Version = "v1alpha1"
+	Kind    = "CapacityConfig"
+	Plural  = "capacityconfigs"
+)
+
+// Package specific errors:
+var (
+	ErrSpecNotFound    = errors.New("spec not found")
+	ErrConflict        = errors.New("capacityConfig has been changed")
+	ErrInvalidResource = errors.New("capacityConfig name and namespace should be provided")
+	ErrInvalidClient   = errors.New("kubernetes rest client should be provided")
+)
+
+// IsNotExist returns a boolean indicating whether the error is known to report
+// that the resource or its spec isn't found.
+func IsNotExist(err error) bool {
+	return apierrors.IsNotFound(errors.Cause(err)) || errors.Cause(err) == ErrSpecNotFound
+}
+
+// IsConflict returns a boolean indicating whether the resource generation doesn't match.
+func IsConflict(err error) bool {
+	return apierrors.IsConflict(errors.Cause(err)) || errors.Cause(err) == ErrConflict
+}
+
+// CapacityConfig is a custom resource that holds a kubescaler config as a spec.
+type CapacityConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   json.RawMessage `json:"spec,omitempty"`
+	Status *Status         `json:"status,omitempty"`
+}
+
+// Status represents the scaler state for the config.
+type Status struct {
+	// ObservedGeneration is the latest spec generation seen by the scaler.
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Workers is a number of pending and running workers.
+	Workers int `json:"workers"`
+	// ReadyWorkers is a number of workers with ready nodes.
+	ReadyWorkers int `json:"readyWorkers"`
+	// Shortfall is a number of workers missing to meet the workersCountMin.
+	Shortfall int `json:"shortfall"`
+	// LastError describes the last failure, e.g. a spec that can't be applied.
+	LastError string `json:"lastError,omitempty"`
+	// BackedOffMachineTypes is a comma separated list of machine types that aren't used after
+	// failures to create workers.
+	BackedOffMachineTypes string `json:"backedOffMachineTypes,omitempty"`
+	// LastUpdateTime is a time of the last status update.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// CRFile represents a file stored as a CapacityConfig resource spec. The resource
+// generation is used as a version, so status updates don't change it.
+type CRFile struct {
+	name      string
+	namespace string
+	client    rest.Interface
+
+	mu sync.Mutex
+	// generation is the latest spec generation seen
+	generation int64
+	// lastStatus is the last written status without an update time
+	lastStatus Status
+}
+
+// New creates the new CRFile.
+func New(name, ns string, client rest.Interface) (*CRFile, error) {
+	name, ns = strings.TrimSpace(name), strings.TrimSpace(ns)
+	if name == "" || ns == "" {
+		return nil, ErrInvalidResource
+	}
+	if client == nil {
+		return nil, ErrInvalidClient
+	}
+
+	return &CRFile{
+		name:      name,
+		namespace: ns,
+		client:    client,
+	}, nil
+}
+
+// Name returns a file name. The spec is stored as JSON.
+func (f *CRFile) Name() string {
+	return f.name + ".json"
+}
+
+// Info describes a file stored as a CapacityConfig resource.
+func (f *CRFile) Info() string {
+	return fmt.Sprintf(`%s/%s CapacityConfig`, f.namespace, f.name)
+}
+
+// Sibling returns a file on the same namespace ConfigMap with the name suffix added
+// (e.g. 'capacity-history'). The ConfigMap is created on the first write.
+func (f *CRFile) Sibling(name string) (*configmap.CMFile, error) {
+	cm, err := configmap.New(f.name, f.namespace, name+".json", corev1client.New(f.client))
+	if err != nil {
+		return nil, err
+	}
+	return cm.Sibling(name)
+}
+
+// Read returns the resource spec and its generation as a version.
+func (f *CRFile) Read() ([]byte, string, error) {
+	cc, err := f.get()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(cc.Spec) == 0 {
+		return nil, "", ErrSpecNotFound
+	}
+
+	f.observe(cc.Generation)
+	return cc.Spec, version(cc.Generation), nil
+}
+
+// Write replaces the resource spec, the resource is created if it doesn't exist.
+// A not empty version should match the resource generation.
+func (f *CRFile) Write(data []byte, ver string) (string, error) {
+	cc, err := f.get()
+	if err != nil {
+		if !apierrors.IsNotFound(errors.Cause(err)) {
+			return "", err
+		}
+		return f.create(data)
+	}
+
+	if ver != "" && ver != version(cc.Generation) {
+		return "", errors.Wrapf(ErrConflict, "%s version %q", f.Info(), ver)
+	}
+
+	// resourceVersion is kept, so the resource modified after the get call isn't overwritten
+	cc.Spec = data
+	cc.Status = nil
+	body, err := json.Marshal(cc)
+	if err != nil {
+		return "", errors.Wrap(err, "encode capacityConfig")
+	}
+	raw, err := f.client.Put().AbsPath(f.path()).Body(body).Do().Raw()
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			return "", errors.Wrapf(ErrConflict, "%s version %q", f.Info(), ver)
+		}
+		return "", errors.Wrap(err, "update capacityConfig")
+	}
+
+	return f.written(raw)
+}
+
+// WriteStatus updates the resource status, nothing is sent if it hasn't been changed.
+func (f *CRFile) WriteStatus(s api.Status) error {
+	f.mu.Lock()
+	status := Status{
+		ObservedGeneration: f.generation,
+		Workers:            s.Workers,
+		ReadyWorkers:       s.ReadyWorkers,
+		Shortfall:          s.Shortfall,
+		LastError:          s.LastError,
+	}
+	if len(s.Backoff) > 0 {
+		names := make([]string, 0, len(s.Backoff))
+		for _, b := range s.Backoff {
+			names = append(names, b.MachineType)
+		}
+		status.BackedOffMachineTypes = strings.Join(names, ",")
+	}
+	unchanged := status == f.lastStatus
+	f.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	updated := status
+	updated.LastUpdateTime = metav1.NewTime(time.Now())
+	patch, err := json.Marshal(map[string]interface{}{"status": updated})
+	if err != nil {
+		return errors.Wrap(err, "encode status")
+	}
+	err = f.client.Patch(types.MergePatchType).AbsPath(f.path(), "status").Body(patch).Do().Error()
+	if err != nil {
+		return errors.Wrapf(err, "update %s status", f.Info())
+	}
+
+	f.mu.Lock()
+	f.lastStatus = status
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *CRFile) get() (*CapacityConfig, error) {
+	raw, err := f.client.Get().AbsPath(f.path()).Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+
+	cc := &CapacityConfig{}
+	if err = json.Unmarshal(raw, cc); err != nil {
+		return nil, errors.Wrap(err, "decode capacityConfig")
+	}
+	return cc, nil
+}
+
+func (f *CRFile) create(data []byte) (string, error) {
+	body, err := json.Marshal(&CapacityConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: Group + "/" + Version,
+			Kind:       Kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      f.name,
+			Namespace: f.namespace,
+		},
+		Spec: data,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "encode capacityConfig")
+	}
+
+	raw, err := f.client.Post().AbsPath(path.Dir(f.path())).Body(body).Do().Raw()
+	if err != nil {
+		return "", errors.Wrap(err, "create capacityConfig")
+	}
+	return f.written(raw)
+}
+
+func (f *CRFile) written(raw []byte) (string, error) {
+	cc := &CapacityConfig{}
+	if err := json.Unmarshal(raw, cc); err != nil {
+		return "", errors.Wrap(err, "decode capacityConfig")
+	}
+
+	f.observe(cc.Generation)
+	return version(cc.Generation), nil
+}
+
+func (f *CRFile) observe(generation int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.generation = generation
+}
+
+func (f *CRFile) path() string {
+	return path.Join("/apis", Group, Version, "namespaces", f.namespace, Plural, f.name)
+}
+
+func version(generation int64) string {
+	return strconv.FormatInt(generation, 10)
+}
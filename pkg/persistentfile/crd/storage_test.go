@@ -0,0 +1,200 @@
+package crd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/supergiant/capacity/pkg/api"
+)
+
+// fakeAPI serves a single CapacityConfig resource, watch events are sent from the channel.
+type fakeAPI struct {
+	mu      sync.Mutex
+	cc      *CapacityConfig
+	version int
+	events  chan *CapacityConfig
+}
+
+func (a *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("watch") == "true" {
+		a.watch(w, r)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/"+Plural) {
+		list := &CapacityConfigList{ListMeta: metav1.ListMeta{ResourceVersion: strconv.Itoa(a.version)}}
+		if a.cc != nil {
+			list.Items = append(list.Items, *a.cc)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	gr := schema.GroupResource{Group: Group, Resource: Plural}
+	status := func(err *apierrors.StatusError) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(err.ErrStatus.Code))
+		json.NewEncoder(w).Encode(err.ErrStatus)
+	}
+
+	switch {
+	case r.Method == http.MethodPost:
+		a.cc = &CapacityConfig{}
+		json.Unmarshal(body, a.cc)
+		a.cc.Generation = 1
+	case a.cc == nil:
+		status(apierrors.NewNotFound(gr, "capacity"))
+		return
+	case r.Method == http.MethodPut:
+		cc := &CapacityConfig{}
+		json.Unmarshal(body, cc)
+		if cc.ResourceVersion != a.cc.ResourceVersion {
+			status(apierrors.NewConflict(gr, "capacity", nil))
+			return
+		}
+		a.cc.Spec = cc.Spec
+		a.cc.Generation++
+	case r.Method == http.MethodPatch:
+		patch := &CapacityConfig{}
+		json.Unmarshal(body, patch)
+		a.cc.Status = patch.Status
+	}
+
+	if r.Method != http.MethodGet {
+		a.version++
+		a.cc.ResourceVersion = strconv.Itoa(a.version)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.cc)
+}
+
+func (a *fakeAPI) watch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case cc := <-a.events:
+			json.NewEncoder(w).Encode(map[string]interface{}{"type": watch.Modified, "object": cc})
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func TestCRFile(t *testing.T) {
+	server := &fakeAPI{}
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	client, err := corev1client.NewForConfig(&rest.Config{Host: srv.URL})
+	require.Nil(t, err)
+	f, err := New("capacity", "kube-system", client.RESTClient())
+	require.Nil(t, err)
+
+	_, _, err = f.Read()
+	require.True(t, IsNotExist(err))
+
+	version, err := f.Write([]byte(`{"clusterName":"test"}`), "")
+	require.Nil(t, err)
+	require.Equal(t, "1", version)
+
+	data, version, err := f.Read()
+	require.Nil(t, err)
+	require.Equal(t, `{"clusterName":"test"}`, string(data))
+	require.Equal(t, "1", version)
+
+	// status updates shouldn't change the version
+	require.Nil(t, f.WriteStatus(api.Status{ReadyWorkers: 2}))
+	require.Equal(t, int64(1), server.cc.Status.ObservedGeneration)
+	require.Equal(t, 2, server.cc.Status.ReadyWorkers)
+
+	version, err = f.Write([]byte(`{"clusterName":"new"}`), "1")
+	require.Nil(t, err)
+	require.Equal(t, "2", version)
+
+	_, err = f.Write([]byte(`{"clusterName":"old"}`), "1")
+	require.True(t, IsConflict(err))
+
+	// unchanged status isn't sent
+	require.Nil(t, f.WriteStatus(api.Status{ReadyWorkers: 2, LastError: "invalid config"}))
+	server.cc.Status = nil
+	require.Nil(t, f.WriteStatus(api.Status{ReadyWorkers: 2, LastError: "invalid config"}))
+	require.Nil(t, server.cc.Status)
+
+	require.Nil(t, f.WriteStatus(api.Status{Backoff: []api.MachineTypeBackoff{{MachineType: "m4.large"}, {MachineType: "m5.large"}}}))
+	require.Equal(t, "m4.large,m5.large", server.cc.Status.BackedOffMachineTypes)
+}
+
+func TestCRFileWatch(t *testing.T) {
+	server := &fakeAPI{
+		cc: &CapacityConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "capacity", Namespace: "kube-system", ResourceVersion: "1"},
+			Spec:       json.RawMessage(`{"clusterName":"test"}`),
+		},
+		events: make(chan *CapacityConfig),
+	}
+	server.cc.Generation = 2
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	client, err := corev1client.NewForConfig(&rest.Config{Host: srv.URL})
+	require.Nil(t, err)
+	f, err := New("capacity", "kube-system", client.RESTClient())
+	require.Nil(t, err)
+	f.observe(1)
+
+	changes := make(chan string)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	require.Nil(t, f.Watch(stopCh, func(data []byte, version string) {
+		changes <- version + ":" + string(data)
+	}))
+
+	// the listed spec is newer than the observed one
+	require.Equal(t, `2:{"clusterName":"test"}`, waitChange(t, changes))
+
+	// status updates don't change the generation
+	status := *server.cc
+	status.ResourceVersion = "2"
+	status.Status = &Status{ReadyWorkers: 1}
+	server.events <- &status
+
+	spec := status
+	spec.ResourceVersion, spec.Generation = "3", 3
+	spec.Spec = json.RawMessage(`{"clusterName":"new"}`)
+	server.events <- &spec
+	require.Equal(t, `3:{"clusterName":"new"}`, waitChange(t, changes))
+}
+
+func waitChange(t *testing.T, changes <-chan string) string {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change has been received")
+	}
+	return ""
+}
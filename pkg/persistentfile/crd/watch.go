@@ -0,0 +1,162 @@
+package crd
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"strconv"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CapacityConfigList is a list of CapacityConfig resources.
+type CapacityConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []CapacityConfig `json:"items"`
+}
+
+// DeepCopyObject implements the runtime.Object interface.
+func (in *CapacityConfig) DeepCopyObject() runtime.Object {
+	out := &CapacityConfig{TypeMeta: in.TypeMeta}
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec != nil {
+		out.Spec = append(json.RawMessage(nil), in.Spec...)
+	}
+	if in.Status != nil {
+		status := *in.Status
+		out.Status = &status
+	}
+	return out
+}
+
+// DeepCopyObject implements the runtime.Object interface.
+func (in *CapacityConfigList) DeepCopyObject() runtime.Object {
+	out := &CapacityConfigList{TypeMeta: in.TypeMeta}
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]CapacityConfig, len(in.Items))
+		for i := range in.Items {
+			out.Items[i] = *in.Items[i].DeepCopyObject().(*CapacityConfig)
+		}
+	}
+	return out
+}
+
+// Watch calls onChange each time the resource spec has been changed. Status updates
+// don't change the generation, so they are ignored.
+func (f *CRFile) Watch(stopCh <-chan struct{}, onChange func(data []byte, version string)) error {
+	selector := fields.OneTermEqualSelector("metadata.name", f.name).String()
+	lw := &cache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			raw, err := f.listRequest(selector, opts).Do().Raw()
+			if err != nil {
+				return nil, err
+			}
+			list := &CapacityConfigList{}
+			if err = json.Unmarshal(raw, list); err != nil {
+				return nil, errors.Wrap(err, "decode capacityConfig list")
+			}
+			return list, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			stream, err := f.listRequest(selector, opts).Param("watch", "true").Stream()
+			if err != nil {
+				return nil, err
+			}
+			return watch.NewStreamWatcher(newEventDecoder(stream), errorReporter{}), nil
+		},
+	}
+
+	f.mu.Lock()
+	last := f.generation
+	f.mu.Unlock()
+	send := func(obj interface{}) {
+		cc, ok := obj.(*CapacityConfig)
+		if !ok || cc.Generation == last || len(cc.Spec) == 0 {
+			return
+		}
+		last = cc.Generation
+		f.observe(cc.Generation)
+		onChange(cc.Spec, version(cc.Generation))
+	}
+
+	_, controller := cache.NewInformer(lw, &CapacityConfig{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: send,
+		UpdateFunc: func(_, obj interface{}) {
+			send(obj)
+		},
+	})
+	go controller.Run(stopCh)
+
+	return nil
+}
+
+// listRequest returns a request for the namespace resources, the REST client doesn't know
+// the CapacityConfig kind, so list options are set as plain parameters.
+func (f *CRFile) listRequest(selector string, opts metav1.ListOptions) *rest.Request {
+	req := f.client.Get().AbsPath(path.Dir(f.path())).Param("fieldSelector", selector)
+	if opts.ResourceVersion != "" {
+		req = req.Param("resourceVersion", opts.ResourceVersion)
+	}
+	if opts.TimeoutSeconds != nil {
+		req = req.Param("timeoutSeconds", strconv.FormatInt(*opts.TimeoutSeconds, 10))
+	}
+	return req
+}
+
+// eventDecoder decodes watch events of the CapacityConfig resources from a JSON stream.
+type eventDecoder struct {
+	stream  io.ReadCloser
+	decoder *json.Decoder
+}
+
+func newEventDecoder(stream io.ReadCloser) *eventDecoder {
+	return &eventDecoder{
+		stream:  stream,
+		decoder: json.NewDecoder(stream),
+	}
+}
+
+func (d *eventDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	var event struct {
+		Type   watch.EventType `json:"type"`
+		Object json.RawMessage `json:"object"`
+	}
+	if err := d.decoder.Decode(&event); err != nil {
+		return "", nil, err
+	}
+
+	if event.Type == watch.Error {
+		status := &metav1.Status{}
+		if err := json.Unmarshal(event.Object, status); err != nil {
+			return "", nil, errors.Wrap(err, "decode watch error")
+		}
+		return event.Type, status, nil
+	}
+
+	cc := &CapacityConfig{}
+	if err := json.Unmarshal(event.Object, cc); err != nil {
+		return "", nil, errors.Wrap(err, "decode capacityConfig")
+	}
+	return event.Type, cc, nil
+}
+
+func (d *eventDecoder) Close() {
+	d.stream.Close()
+}
+
+// errorReporter turns stream errors into watch error events.
+type errorReporter struct{}
+
+func (errorReporter) AsObject(err error) runtime.Object {
+	return &apierrors.NewInternalError(err).ErrStatus
+}
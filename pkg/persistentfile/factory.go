@@ -5,8 +5,10 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
 
 	"github.com/supergiant/capacity/pkg/persistentfile/configmap"
+	"github.com/supergiant/capacity/pkg/persistentfile/crd"
 	"github.com/supergiant/capacity/pkg/persistentfile/file"
 )
 
@@ -15,10 +17,17 @@ type FileProvider string
 const (
 	FSFile        FileProvider = "fs"
 	ConfigMapFile FileProvider = "cm"
+	ResourceFile  FileProvider = "cr"
 )
 
 func IsNotExist(err error) bool {
-	return file.IsNotExist(err) || configmap.IsNotExist(err)
+	return file.IsNotExist(err) || configmap.IsNotExist(err) || crd.IsNotExist(err)
+}
+
+// IsConflict returns a boolean indicating whether the file has been changed since
+// the provided version.
+func IsConflict(err error) bool {
+	return file.IsConflict(err) || configmap.IsConflict(err) || crd.IsConflict(err)
 }
 
 type Config struct {
@@ -31,6 +40,10 @@ type Config struct {
 	ConfigMapNamespace string
 	Key                string
 	ConfigMapClient    v1.ConfigMapsGetter
+	// CapacityConfig resource parameters
+	ResourceName      string
+	ResourceNamespace string
+	RESTClient        rest.Interface
 }
 
 func New(c Config) (Interface, error) {
@@ -39,6 +52,22 @@ func New(c Config) (Interface, error) {
 		return file.New(c.Path, c.Perm)
 	case ConfigMapFile:
 		return configmap.New(c.ConfigMapName, c.ConfigMapNamespace, c.Key, c.ConfigMapClient)
+	case ResourceFile:
+		return crd.New(c.ResourceName, c.ResourceNamespace, c.RESTClient)
+	}
+	return nil, errors.New("unknown file provider")
+}
+
+// Sibling returns a file stored next to the provided one, e.g. a file in the same
+// directory or a ConfigMap in the same namespace.
+func Sibling(f Interface, name string) (Interface, error) {
+	switch f := f.(type) {
+	case *file.FSFile:
+		return f.Sibling(name)
+	case *configmap.CMFile:
+		return f.Sibling(name)
+	case *crd.CRFile:
+		return f.Sibling(name)
 	}
 	return nil, errors.New("unknown file provider")
 }
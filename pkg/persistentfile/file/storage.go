@@ -1,6 +1,8 @@
 package file
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -12,6 +14,7 @@ import (
 // Package specific errors:
 var (
 	ErrInvalidFilePath = errors.New("invalid filepath")
+	ErrConflict        = errors.New("file has been changed")
 )
 
 // IsNotExist is a wrapper for os.IsNotExist.
@@ -19,6 +22,11 @@ func IsNotExist(err error) bool {
 	return os.IsNotExist(err)
 }
 
+// IsConflict returns a boolean indicating whether the file version doesn't match.
+func IsConflict(err error) bool {
+	return errors.Cause(err) == ErrConflict
+}
+
 // FSFile represents a filesystem file.
 type FSFile struct {
 	path        string
@@ -36,18 +44,53 @@ func New(path string, fm os.FileMode) (*FSFile, error) {
 	}, nil
 }
 
+// Sibling returns a file on the same directory with the name suffix added.
+func (f FSFile) Sibling(name string) (*FSFile, error) {
+	return New(f.path+"."+name, f.permissions)
+}
+
+// Name returns the file path.
+func (f FSFile) Name() string {
+	return f.path
+}
+
 // Info describes a file path.
 func (f FSFile) Info() string {
 	return fmt.Sprintf("%q file", f.path)
 }
 
-// Read reads the file on provided path and returns the contents.
-func (f FSFile) Read() ([]byte, error) {
-	return ioutil.ReadFile(f.path)
+// Read reads the file on provided path and returns the contents and a hash of it
+// as a version.
+func (f FSFile) Read() ([]byte, string, error) {
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, Version(data), nil
 }
 
 // Write stores data to file. If the file does not exist it will be created,
-// otherwise it truncates the file before writing.
-func (f FSFile) Write(data []byte) error {
-	return ioutil.WriteFile(f.path, data, f.permissions)
+// otherwise it truncates the file before writing. A not empty version should match
+// the current file contents.
+func (f FSFile) Write(data []byte, version string) (string, error) {
+	if version != "" {
+		_, current, err := f.Read()
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		if current != version {
+			return "", errors.Wrapf(ErrConflict, "%s version %q", f.Info(), version)
+		}
+	}
+
+	if err := ioutil.WriteFile(f.path, data, f.permissions); err != nil {
+		return "", err
+	}
+	return Version(data), nil
+}
+
+// Version returns a hash of the file contents.
+func Version(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
 }
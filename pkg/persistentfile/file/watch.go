@@ -0,0 +1,49 @@
+package file
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+
+	"github.com/supergiant/capacity/pkg/log"
+)
+
+// Watch calls onChange each time the file contents have been changed. The parent directory
+// is watched, so atomic renames and ConfigMap volume updates are handled as well.
+func (f FSFile) Watch(stopCh <-chan struct{}, onChange func(data []byte, version string)) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "create watcher")
+	}
+	if err = w.Add(filepath.Dir(f.path)); err != nil {
+		w.Close()
+		return errors.Wrapf(err, "watch %s", filepath.Dir(f.path))
+	}
+
+	last, _ := ioutil.ReadFile(f.path)
+	go func() {
+		defer w.Close()
+
+		for {
+			select {
+			case <-stopCh:
+				return
+			case err := <-w.Errors:
+				log.Errorf("persistentfile: watch %s: %v", f.Info(), err)
+			case <-w.Events:
+				data, err := ioutil.ReadFile(f.path)
+				if err != nil || bytes.Equal(data, last) {
+					// file could be removed or partially written, wait for the next event
+					continue
+				}
+				last = data
+				onChange(data, Version(data))
+			}
+		}
+	}()
+
+	return nil
+}
@@ -2,16 +2,26 @@ package persistentfile
 
 import (
 	"github.com/supergiant/capacity/pkg/persistentfile/configmap"
+	"github.com/supergiant/capacity/pkg/persistentfile/crd"
 	"github.com/supergiant/capacity/pkg/persistentfile/file"
 )
 
 var (
 	_ Interface = &file.FSFile{}
 	_ Interface = &configmap.CMFile{}
+	_ Interface = &crd.CRFile{}
 )
 
 type Interface interface {
 	Info() string
-	Read() ([]byte, error)
-	Write(data []byte) error
+	// Name returns a file name, it could be used to detect a file format.
+	Name() string
+	// Read returns the file contents and its version.
+	Read() (data []byte, version string, err error)
+	// Write stores data if the file version matches the provided one (an empty version
+	// disables the check) and returns a new version.
+	Write(data []byte, version string) (string, error)
+	// Watch calls onChange with new file contents and its version on external changes
+	// until the stopCh is closed.
+	Watch(stopCh <-chan struct{}, onChange func(data []byte, version string)) error
 }
@@ -2,6 +2,7 @@ package instancetypes
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -21,7 +22,7 @@ type VM struct {
 	Description string
 }
 
-func (s manager) regionTypes(region string) ([]VM, error) {
+func (s *manager) regionTypes(region string) ([]VM, error) {
 	s.vmu.RLock()
 	defer s.vmu.RUnlock()
 
@@ -34,3 +35,20 @@ func (s manager) regionTypes(region string) ([]VM, error) {
 	}
 	return s.regionVMs[region], nil
 }
+
+func (s *manager) regions() []string {
+	s.vmu.RLock()
+	defer s.vmu.RUnlock()
+
+	regions := make([]string, 0, len(s.regionVMs))
+	for region := range s.regionVMs {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// Regions returns a sorted list of supported regions.
+func Regions() []string {
+	return awsMachines.regions()
+}
@@ -0,0 +1,108 @@
+package aws
+
+// eniLimits is a number of network interfaces and IPv4 addresses per interface of an instance type.
+type eniLimits struct {
+	enis, ipsPerENI int
+}
+
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/using-eni.html#AvailableIpPerENI
+var instanceENILimits = map[string]eniLimits{
+	"c3.large":    {3, 10},
+	"c3.xlarge":   {4, 15},
+	"c3.2xlarge":  {4, 15},
+	"c3.4xlarge":  {8, 30},
+	"c3.8xlarge":  {8, 30},
+	"c4.large":    {3, 10},
+	"c4.xlarge":   {4, 15},
+	"c4.2xlarge":  {4, 15},
+	"c4.4xlarge":  {8, 30},
+	"c4.8xlarge":  {8, 30},
+	"c5.large":    {3, 10},
+	"c5.xlarge":   {4, 15},
+	"c5.2xlarge":  {4, 15},
+	"c5.4xlarge":  {8, 30},
+	"c5.9xlarge":  {8, 30},
+	"c5.18xlarge": {15, 50},
+	"d2.xlarge":   {4, 15},
+	"d2.2xlarge":  {4, 15},
+	"d2.4xlarge":  {8, 30},
+	"d2.8xlarge":  {8, 30},
+	"g2.2xlarge":  {4, 15},
+	"g2.8xlarge":  {8, 30},
+	"g3.4xlarge":  {8, 30},
+	"g3.8xlarge":  {8, 30},
+	"g3.16xlarge": {15, 50},
+	"i3.large":    {3, 10},
+	"i3.xlarge":   {4, 15},
+	"i3.2xlarge":  {4, 15},
+	"i3.4xlarge":  {8, 30},
+	"i3.8xlarge":  {8, 30},
+	"i3.16xlarge": {15, 50},
+	"m3.medium":   {2, 6},
+	"m3.large":    {3, 10},
+	"m3.xlarge":   {4, 15},
+	"m3.2xlarge":  {4, 30},
+	"m4.large":    {2, 10},
+	"m4.xlarge":   {4, 15},
+	"m4.2xlarge":  {4, 15},
+	"m4.4xlarge":  {8, 30},
+	"m4.10xlarge": {8, 30},
+	"m4.16xlarge": {8, 30},
+	"m5.large":    {3, 10},
+	"m5.xlarge":   {4, 15},
+	"m5.2xlarge":  {4, 15},
+	"m5.4xlarge":  {8, 30},
+	"m5.12xlarge": {8, 30},
+	"m5.24xlarge": {15, 50},
+	"p2.xlarge":   {4, 15},
+	"p2.8xlarge":  {8, 30},
+	"p2.16xlarge": {8, 30},
+	"p3.2xlarge":  {4, 15},
+	"p3.8xlarge":  {8, 30},
+	"p3.16xlarge": {8, 30},
+	"r3.large":    {3, 10},
+	"r3.xlarge":   {4, 15},
+	"r3.2xlarge":  {4, 15},
+	"r3.4xlarge":  {8, 30},
+	"r3.8xlarge":  {8, 30},
+	"r4.large":    {3, 10},
+	"r4.xlarge":   {4, 15},
+	"r4.2xlarge":  {4, 15},
+	"r4.4xlarge":  {8, 30},
+	"r4.8xlarge":  {8, 30},
+	"r4.16xlarge": {15, 50},
+	"r5.large":    {3, 10},
+	"r5.xlarge":   {4, 15},
+	"r5.2xlarge":  {4, 15},
+	"r5.4xlarge":  {8, 30},
+	"r5.12xlarge": {8, 30},
+	"r5.24xlarge": {15, 50},
+	"t2.nano":     {2, 2},
+	"t2.micro":    {2, 2},
+	"t2.small":    {3, 4},
+	"t2.medium":   {3, 6},
+	"t2.large":    {3, 12},
+	"t2.xlarge":   {3, 15},
+	"t2.2xlarge":  {3, 15},
+	"t3.nano":     {2, 2},
+	"t3.micro":    {2, 2},
+	"t3.small":    {3, 4},
+	"t3.medium":   {3, 6},
+	"t3.large":    {3, 12},
+	"t3.xlarge":   {4, 15},
+	"t3.2xlarge":  {4, 15},
+	"x1.16xlarge": {8, 30},
+	"x1.32xlarge": {8, 30},
+}
+
+// eniMaxPods returns a number of pods an instance of the type is able to run with the VPC CNI
+// plugin: each pod takes a secondary IP address, the primary ones are used by the node and
+// two host network pods (aws-node and kube-proxy) don't need them. Zero is returned for
+// unknown types.
+func eniMaxPods(instanceType string) int {
+	l, ok := instanceENILimits[instanceType]
+	if !ok {
+		return 0
+	}
+	return l.enis*(l.ipsPerENI-1) + 2
+}
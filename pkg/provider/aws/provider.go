@@ -3,18 +3,30 @@ package aws
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/pkg/errors"
 	"github.com/supergiant/control/pkg/clouds/aws"
 	"k8s.io/apimachinery/pkg/api/resource"
 
+	"github.com/supergiant/capacity/pkg/log"
 	"github.com/supergiant/capacity/pkg/provider"
 	"github.com/supergiant/capacity/pkg/provider/aws/instancetypes"
 )
 
+// describeSubnetTimeout limits a request for the awsSubnetID zone.
+const describeSubnetTimeout = 10 * time.Second
+
 // Provider name:
 const (
 	Name = "aws"
@@ -35,8 +47,39 @@ const (
 	VolDeviceName  = "awsVolDeviceName"
 	EBSOptimized   = "ebsOptimized"
 	Tags           = "awsTags"
+	// VPCCNI limits a number of pods on instances by their network interfaces, it should be set
+	// to 'true' if the cluster uses the amazon-vpc-cni-k8s plugin.
+	VPCCNI = "awsVPCCNI"
+	// ZoneSubnets is a map of availability zones to subnets for machines that should run in
+	// a zone, e.g. 'us-east-1a=subnet-1,us-east-1b=subnet-2'.
+	ZoneSubnets = "awsZoneSubnets"
 )
 
+// Keys is a list of supported instance parameters.
+var Keys = []string{
+	KeyID,
+	SecretKey,
+	Region,
+	KeyName,
+	ImageID,
+	IAMRole,
+	SecurityGroups,
+	SubnetID,
+	VolType,
+	VolSize,
+	VolDeviceName,
+	EBSOptimized,
+	Tags,
+	VPCCNI,
+	ZoneSubnets,
+}
+
+// SensitiveKeys is a list of instance parameters that shouldn't be exposed.
+var SensitiveKeys = []string{
+	KeyID,
+	SecretKey,
+}
+
 type Config struct {
 	KeyName        string
 	ImageID        string
@@ -48,6 +91,8 @@ type Config struct {
 	VolDeviceName  string
 	EBSOptimized   *bool
 	Tags           map[string]string
+	VPCCNI         bool
+	ZoneSubnets    map[string]string
 }
 
 type Provider struct {
@@ -55,6 +100,12 @@ type Provider struct {
 	region      string
 	instConf    Config
 	client      *aws.Client
+	// ec2 is used for requests the client doesn't support, e.g. to describe subnets
+	ec2 ec2iface.EC2API
+
+	mu sync.Mutex
+	// subnetZone is an availability zone of the SubnetID, it's got on the first use
+	subnetZone string
 }
 
 func New(clusterName string, config provider.Config) (*Provider, error) {
@@ -78,6 +129,8 @@ func New(clusterName string, config provider.Config) (*Provider, error) {
 		return nil, errors.Wrapf(err, "invalid %q volume size", config[VolSize])
 	}
 
+	vpcCNI := parseBool(config[VPCCNI])
+
 	if config[VolDeviceName] == "" {
 		config[VolDeviceName] = "/dev/sda1"
 	}
@@ -96,8 +149,14 @@ func New(clusterName string, config provider.Config) (*Provider, error) {
 			VolDeviceName:  config[VolDeviceName],
 			EBSOptimized:   parseBool(config[EBSOptimized]),
 			Tags:           tags,
+			VPCCNI:         vpcCNI != nil && *vpcCNI,
+			ZoneSubnets:    provider.ParseMap(config[ZoneSubnets]),
 		},
 		client: client,
+		ec2: ec2.New(session.New(&awssdk.Config{
+			Region:      awssdk.String(region),
+			Credentials: credentials.NewStaticCredentials(key, secret, ""),
+		})),
 	}, nil
 }
 
@@ -112,6 +171,13 @@ func (p *Provider) MachineTypes(_ context.Context) ([]*provider.MachineType, err
 		return nil, err
 	}
 
+	// the root volume is shared by all the machine types
+	storage := strconv.FormatInt(p.instConf.VolSize, 10)
+	storageRes, err := parseGiB(storage)
+	if err != nil {
+		return nil, errors.Wrapf(err, "volume size: parse %s", storage)
+	}
+
 	mTypes := make([]*provider.MachineType, 0, len(instTypes))
 	for _, vm := range instTypes {
 		mem, err := parseGiB(vm.MemoryGiB)
@@ -126,14 +192,26 @@ func (p *Provider) MachineTypes(_ context.Context) ([]*provider.MachineType, err
 		if err != nil {
 			return nil, errors.Wrapf(err, "hour price: parse %s", vm.PriceHour)
 		}
+		gpu, err := parseGPU(vm.GPU)
+		if err != nil {
+			return nil, errors.Wrapf(err, "gpu: parse %s", vm.GPU)
+		}
+		maxPods := 0
+		if p.instConf.VPCCNI {
+			maxPods = eniMaxPods(vm.Name)
+		}
 		mTypes = append(mTypes, &provider.MachineType{
-			Name:           vm.Name,
-			Memory:         vm.MemoryGiB,
-			CPU:            vm.VCPU,
-			MemoryResource: mem,
-			CPUResource:    cpu,
-			PriceHour:      price,
-			Description:    vm.Description,
+			Name:            vm.Name,
+			Memory:          vm.MemoryGiB,
+			CPU:             vm.VCPU,
+			MemoryResource:  mem,
+			CPUResource:     cpu,
+			PriceHour:       price,
+			Description:     vm.Description,
+			GPU:             gpu,
+			Storage:         storage,
+			StorageResource: storageRes,
+			MaxPods:         maxPods,
 		})
 	}
 
@@ -151,7 +229,7 @@ func (p *Provider) GetMachine(ctx context.Context, id string) (*provider.Machine
 func (p *Provider) Machines(ctx context.Context) ([]*provider.Machine, error) {
 	insts, err := p.client.ListRegionInstances(ctx, p.region, nil)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
 	machines := make([]*provider.Machine, 0)
@@ -165,8 +243,54 @@ func (p *Provider) Machines(ctx context.Context) ([]*provider.Machine, error) {
 	return machines, nil
 }
 
+// Zones returns availability zones with configured subnets including the awsSubnetID one.
+func (p *Provider) Zones() []string {
+	zones := make([]string, 0, len(p.instConf.ZoneSubnets)+1)
+	for zone := range p.instConf.ZoneSubnets {
+		zones = append(zones, zone)
+	}
+	if zone := p.defaultSubnetZone(); zone != "" && p.instConf.ZoneSubnets[zone] == "" {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+// defaultSubnetZone returns an availability zone of the awsSubnetID, an empty one is returned if
+// the subnet isn't set or its zone can't be got. Failed requests are retried on the next call.
+func (p *Provider) defaultSubnetZone() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.subnetZone != "" || p.instConf.SubnetID == "" || p.ec2 == nil {
+		return p.subnetZone
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), describeSubnetTimeout)
+	defer cancel()
+	out, err := p.ec2.DescribeSubnetsWithContext(ctx, &ec2.DescribeSubnetsInput{
+		SubnetIds: []*string{awssdk.String(p.instConf.SubnetID)},
+	})
+	if err != nil {
+		log.Errorf("aws: describe %s subnet: %v", p.instConf.SubnetID, err)
+		return ""
+	}
+	if len(out.Subnets) > 0 {
+		p.subnetZone = awssdk.StringValue(out.Subnets[0].AvailabilityZone)
+	}
+	return p.subnetZone
+}
+
 func (p *Provider) CreateMachine(ctx context.Context, name, mtype, clusterRole, userData string, config provider.Config) (*provider.Machine, error) {
 	// TODO: merge and validate config parameters
+	subnetID := p.instConf.SubnetID
+	if zone := config[provider.ZoneKey]; zone != "" {
+		if zoneSubnetID, ok := p.instConf.ZoneSubnets[zone]; ok {
+			subnetID = zoneSubnetID
+		} else if zone != p.defaultSubnetZone() {
+			return nil, errors.Errorf("no subnet for the %s zone", zone)
+		}
+	}
 
 	inst, err := p.client.CreateInstance(ctx, aws.InstanceConfig{
 		TagName:          name,
@@ -178,7 +302,7 @@ func (p *Provider) CreateMachine(ctx context.Context, name, mtype, clusterRole,
 		KeyName:          p.instConf.KeyName,
 		IAMRole:          p.instConf.IAMRole,
 		SecurityGroups:   p.instConf.SecurityGroups,
-		SubnetID:         p.instConf.SubnetID,
+		SubnetID:         subnetID,
 		VolumeType:       p.instConf.VolType,
 		VolumeSize:       p.instConf.VolSize,
 		VolumeDeviceName: p.instConf.VolDeviceName,
@@ -188,12 +312,28 @@ func (p *Provider) CreateMachine(ctx context.Context, name, mtype, clusterRole,
 		HasPublicAddr:    true,
 	})
 	if err != nil {
+		if isMachineTypeError(err) {
+			return nil, &provider.MachineTypeError{Err: err}
+		}
 		return nil, err
 	}
 
 	return machineFrom(inst), nil
 }
 
+// machineTypeErrorCodes are EC2 errors of the instance type, other types may still be launched.
+var machineTypeErrorCodes = map[string]bool{
+	"InsufficientInstanceCapacity": true,
+	"InstanceLimitExceeded":        true,
+	"VcpuLimitExceeded":            true,
+	"Unsupported":                  true,
+}
+
+func isMachineTypeError(err error) bool {
+	awsErr, ok := errors.Cause(err).(awserr.Error)
+	return ok && machineTypeErrorCodes[awsErr.Code()]
+}
+
 func (p *Provider) DeleteMachine(ctx context.Context, id string) (*provider.Machine, error) {
 	instState, err := p.client.DeleteInstance(ctx, p.region, id)
 	if err != nil {
@@ -227,6 +367,14 @@ func parseVCPU(vcpu string) (resource.Quantity, error) {
 	return resource.ParseQuantity(vcpu)
 }
 
+// parseGPU returns a number of GPUs, it's empty for types without them.
+func parseGPU(gpu string) (int, error) {
+	if gpu == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(gpu)
+}
+
 func getName(tags []*ec2.Tag) string {
 	for _, tag := range tags {
 		if *tag.Key == "Name" {
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 describes subnets of the zones map, other methods of the interface aren't implemented.
+type fakeEC2 struct {
+	ec2iface.EC2API
+	zones map[string]string
+	calls int
+}
+
+func (f *fakeEC2) DescribeSubnetsWithContext(ctx awssdk.Context, in *ec2.DescribeSubnetsInput, opts ...request.Option) (*ec2.DescribeSubnetsOutput, error) {
+	f.calls++
+	zone, ok := f.zones[awssdk.StringValue(in.SubnetIds[0])]
+	if !ok {
+		return nil, errors.New("subnet not found")
+	}
+	return &ec2.DescribeSubnetsOutput{
+		Subnets: []*ec2.Subnet{{SubnetId: in.SubnetIds[0], AvailabilityZone: awssdk.String(zone)}},
+	}, nil
+}
+
+func TestZones(t *testing.T) {
+	svc := &fakeEC2{zones: map[string]string{"subnet-a": "us-east-1a", "subnet-b": "us-east-1b"}}
+
+	tcs := []struct {
+		subnetID      string
+		zoneSubnets   map[string]string
+		expectedZones []string
+	}{
+		{
+			expectedZones: []string{},
+		},
+		{
+			subnetID:      "subnet-a",
+			expectedZones: []string{"us-east-1a"},
+		},
+		{
+			subnetID:      "subnet-a",
+			zoneSubnets:   map[string]string{"us-east-1b": "subnet-b", "us-east-1c": "subnet-c"},
+			expectedZones: []string{"us-east-1a", "us-east-1b", "us-east-1c"},
+		},
+		{
+			subnetID:      "subnet-unknown",
+			zoneSubnets:   map[string]string{"us-east-1b": "subnet-b"},
+			expectedZones: []string{"us-east-1b"},
+		},
+	}
+
+	for i, tc := range tcs {
+		p := &Provider{
+			instConf: Config{SubnetID: tc.subnetID, ZoneSubnets: tc.zoneSubnets},
+			ec2:      svc,
+		}
+		require.Equalf(t, tc.expectedZones, p.Zones(), "TC#%d", i+1)
+	}
+
+	// the subnet zone is got once
+	svc.calls = 0
+	p := &Provider{instConf: Config{SubnetID: "subnet-a"}, ec2: svc}
+	p.Zones()
+	p.Zones()
+	require.Equal(t, 1, svc.calls)
+}
+
+func TestIsMachineTypeError(t *testing.T) {
+	tcs := []struct {
+		err      error
+		expected bool
+	}{
+		{err: errors.New("InsufficientInstanceCapacity")},
+		{err: awserr.New("UnauthorizedOperation", "", nil)},
+		{err: awserr.New("InsufficientInstanceCapacity", "", nil), expected: true},
+		{err: errors.Wrap(awserr.New("InstanceLimitExceeded", "", nil), "aws: run instance"), expected: true},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expected, isMachineTypeError(tc.err), "TC#%d", i+1)
+	}
+}
@@ -0,0 +1,70 @@
+package aws
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/supergiant/capacity/pkg/provider"
+	"github.com/supergiant/capacity/pkg/provider/aws/instancetypes"
+)
+
+// Validate checks instance parameters and machine types against the region catalog.
+func Validate(config provider.Config, machineTypes []string, fldPath, mtypesPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	// don't expose credentials on validation errors
+	for _, k := range []string{KeyID, SecretKey, ImageID} {
+		if strings.TrimSpace(config[k]) == "" {
+			errs = append(errs, field.Required(fldPath.Key(k), ""))
+		}
+	}
+
+	if config[VolSize] == "" {
+		errs = append(errs, field.Required(fldPath.Key(VolSize), "root volume size in GiB"))
+	} else if size, err := strconv.ParseInt(config[VolSize], 10, 64); err != nil || size <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Key(VolSize), config[VolSize], "should be a positive number of GiB"))
+	}
+
+	for _, k := range []string{EBSOptimized, VPCCNI} {
+		if config[k] == "" {
+			continue
+		}
+		if _, err := strconv.ParseBool(config[k]); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Key(k), config[k], "should be a boolean"))
+		}
+	}
+
+	if config[ZoneSubnets] != "" {
+		for _, pair := range strings.Split(config[ZoneSubnets], provider.ListSep) {
+			kv := strings.Split(pair, provider.KeyValSep)
+			if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+				errs = append(errs, field.Invalid(fldPath.Key(ZoneSubnets), config[ZoneSubnets],
+					"should be a list of zone=subnet pairs, e.g. 'us-east-1a=subnet-1,us-east-1b=subnet-2'"))
+				break
+			}
+		}
+	}
+
+	region := config[Region]
+	if region == "" {
+		return append(errs, field.Required(fldPath.Key(Region), ""))
+	}
+	vms, err := instancetypes.RegionTypes(region)
+	if err != nil {
+		return append(errs, field.NotSupported(fldPath.Key(Region), region, instancetypes.Regions()))
+	}
+
+	known := make(map[string]bool, len(vms))
+	for _, vm := range vms {
+		known[vm.Name] = true
+	}
+	for i, name := range machineTypes {
+		if !known[name] {
+			errs = append(errs, field.NotFound(mtypesPath.Index(i), name))
+		}
+	}
+
+	return errs
+}
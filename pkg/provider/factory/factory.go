@@ -3,6 +3,8 @@ package factory
 import (
 	"strings"
 
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
 	"github.com/supergiant/capacity/pkg/provider"
 	"github.com/supergiant/capacity/pkg/provider/aws"
 )
@@ -19,3 +21,34 @@ func New(clusterName, provider string, config provider.Config) (provider.Provide
 	}
 	return nil, ErrNotSuported
 }
+
+// Keys returns a list of supported provider parameters.
+func Keys(provider string) []string {
+	switch provider {
+	case aws.Name:
+		return aws.Keys
+	}
+	return nil
+}
+
+// SensitiveKeys returns a list of provider parameters that shouldn't be exposed.
+func SensitiveKeys(provider string) []string {
+	switch provider {
+	case aws.Name:
+		return aws.SensitiveKeys
+	}
+	return nil
+}
+
+// Validate checks provider parameters against the provider schema and machine types
+// against its catalog.
+func Validate(provider string, config provider.Config, machineTypes []string) field.ErrorList {
+	providerPath := field.NewPath("provider")
+	mtypesPath := field.NewPath("machineTypes")
+
+	switch provider {
+	case aws.Name:
+		return aws.Validate(config, machineTypes, providerPath, mtypesPath)
+	}
+	return field.ErrorList{field.NotSupported(field.NewPath("providerName"), provider, []string{aws.Name})}
+}
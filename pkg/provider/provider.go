@@ -13,6 +13,12 @@ const (
 	TagCluster = "KubernetesCluster"
 )
 
+// ResourceGPU is a name of the GPU extended resource.
+const ResourceGPU = "nvidia.com/gpu"
+
+// ZoneKey is a CreateMachine config parameter with an availability zone for the machine.
+const ZoneKey = "zone"
+
 // Separators for custom lists and maps:
 // list: "val1,val2"
 // map:  "key1=val1,key2=val2"
@@ -21,6 +27,31 @@ const (
 	KeyValSep = "="
 )
 
+// MachineTypeError is returned by CreateMachine if machines of the type can't be created at the time,
+// e.g. the provider is out of capacity or the type quota is exceeded. Other types may still be created.
+type MachineTypeError struct {
+	Err error
+}
+
+func (e *MachineTypeError) Error() string {
+	return e.Err.Error()
+}
+
+// IsMachineTypeError reports whether the error or any of its causes is a MachineTypeError.
+func IsMachineTypeError(err error) bool {
+	for err != nil {
+		if _, ok := err.(*MachineTypeError); ok {
+			return true
+		}
+		causer, ok := err.(interface{ Cause() error })
+		if !ok {
+			return false
+		}
+		err = causer.Cause()
+	}
+	return false
+}
+
 type Machine struct {
 	ID                string    `json:"id"`
 	Name              string    `json:"name"`
@@ -38,6 +69,37 @@ type MachineType struct {
 	CPUResource    resource.Quantity `json:"-"`
 	PriceHour      float64           `json:"priceHour"`
 	Description    string            `json:"description"`
+	// GPU is a number of GPUs, they are advertised by nodes as the 'nvidia.com/gpu' resource.
+	GPU int `json:"gpu,omitempty"`
+	// ExtendedResources are other resources advertised by nodes of the type, e.g. 'example.com/fpga'.
+	ExtendedResources map[string]resource.Quantity `json:"extendedResources,omitempty"`
+	// Storage is a root volume size in GiB available for the ephemeral storage of pods.
+	Storage         string            `json:"storage,omitempty"`
+	StorageResource resource.Quantity `json:"-"`
+	// MaxPods is a number of pods a node of the type is able to run, zero if it isn't known.
+	MaxPods int `json:"maxPods,omitempty"`
+}
+
+// Extended returns extended resources of the machine type including GPUs.
+func (m *MachineType) Extended() map[string]resource.Quantity {
+	out := make(map[string]resource.Quantity, len(m.ExtendedResources)+1)
+	if m.GPU > 0 {
+		out[ResourceGPU] = *resource.NewQuantity(int64(m.GPU), resource.DecimalSI)
+	}
+	for name, q := range m.ExtendedResources {
+		out[name] = q
+	}
+	return out
+}
+
+// HasExtended reports whether the machine type has GPUs or other extended resources.
+func (m *MachineType) HasExtended() bool {
+	for _, q := range m.Extended() {
+		if !q.IsZero() {
+			return true
+		}
+	}
+	return false
 }
 
 func SortedMachineTypes(mtypes []*MachineType) []*MachineType {
@@ -63,6 +125,12 @@ func SortedMachineTypes(mtypes []*MachineType) []*MachineType {
 
 type Config map[string]string
 
+// Zoner is implemented by providers able to create machines in a few availability zones.
+type Zoner interface {
+	// Zones returns a sorted list of zones a machine could be created in.
+	Zones() []string
+}
+
 type Provider interface {
 	Name() string
 	ParseMachineID(providerID string) (string, error)
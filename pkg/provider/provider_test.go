@@ -3,6 +3,7 @@ package provider
 import (
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
@@ -76,3 +77,22 @@ func TestSortedMachineTypes(t *testing.T) {
 		require.Equalf(t, tc.expected, SortedMachineTypes(tc.in), "TC#%d", i+1)
 	}
 }
+
+func TestIsMachineTypeError(t *testing.T) {
+	errType := &MachineTypeError{Err: errors.New("out of capacity")}
+
+	tcs := []struct {
+		err      error
+		expected bool
+	}{
+		{},
+		{err: errors.New("unauthorized")},
+		{err: errType, expected: true},
+		{err: errors.Wrap(errType, "create a worker"), expected: true},
+		{err: errors.Wrap(errors.New("unauthorized"), "create a worker")},
+	}
+
+	for i, tc := range tcs {
+		require.Equalf(t, tc.expected, IsMachineTypeError(tc.err), "TC#%d", i+1)
+	}
+}